@@ -6,6 +6,7 @@ REPO_VERSION_FILE="${ROOT_DIR}/scripts/restic-version.yaml"
 CONFIG_BASE_DIR="${XDG_CONFIG_HOME:-${HOME}/.config}/backup"
 CONFIG_FILE="${CONFIG_BASE_DIR}/config.yaml"
 INSTALL_SCRIPT="${ROOT_DIR}/scripts/install_restic_wsl_fedora.sh"
+GO_VERSION_FILE="${ROOT_DIR}/src/restic_version.go"
 
 extract_dnf_versions() {
   dnf --showduplicates --quiet list restic 2>/dev/null \
@@ -78,4 +79,7 @@ fi
 set_yaml_restic_version "${REPO_VERSION_FILE}" "${LATEST_DNF_VERSION}"
 echo "Updated ${REPO_VERSION_FILE} to restic_version: ${LATEST_DNF_VERSION}" >&2
 
+sed -i -E "s/^const PinnedResticVersion = \".*\"/const PinnedResticVersion = \"${LATEST_DNF_VERSION}\"/" "${GO_VERSION_FILE}"
+echo "Updated ${GO_VERSION_FILE} to PinnedResticVersion: ${LATEST_DNF_VERSION}" >&2
+
 "${INSTALL_SCRIPT}"
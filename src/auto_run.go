@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+var cadenceIntervals = map[string]time.Duration{
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// Scheduled runs drift by the timer's randomized delay and by how long the
+// previous run took, so a cadence is due slightly before its full interval.
+const cadenceDueTolerance = 2 * time.Hour
+
+// Widest cadence first: that is the order auto runs execute in and the
+// direction in which one cadence can supersede another.
+var cadencesBySpan = []string{"monthly", "weekly", "daily"}
+
+type CadenceDue struct {
+	Cadence      string
+	Due          bool
+	LastSuccess  time.Time
+	SupersededBy string
+}
+
+// EvaluateDueCadences treats a cadence as due when any target has never
+// completed it or when the oldest target's last success is overdue.
+func EvaluateDueCadences(records []RunRecord, targets []string, now time.Time) []CadenceDue {
+	lastRuns := LastSuccessfulRuns(records)
+	evaluations := make([]CadenceDue, 0, len(cadencesBySpan))
+	for _, cadence := range cadencesBySpan {
+		evaluation := CadenceDue{Cadence: cadence}
+		for _, target := range targets {
+			lastSuccess, ok := lastRuns[cadence][target]
+			if !ok {
+				evaluation.Due = true
+				evaluation.LastSuccess = time.Time{}
+				break
+			}
+			if evaluation.LastSuccess.IsZero() || lastSuccess.Before(evaluation.LastSuccess) {
+				evaluation.LastSuccess = lastSuccess
+			}
+		}
+		if !evaluation.Due && now.Sub(evaluation.LastSuccess) >= cadenceIntervals[cadence]-cadenceDueTolerance {
+			evaluation.Due = true
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations
+}
+
+// ApplyCadenceSupersession marks a due cadence as covered when a wider due
+// cadence captures at least the same paths for every target today.
+func ApplyCadenceSupersession(evaluations []CadenceDue, targets []string, config AppConfig) []CadenceDue {
+	updated := append([]CadenceDue{}, evaluations...)
+	for lowerIndex := range updated {
+		if !updated[lowerIndex].Due {
+			continue
+		}
+		for higherIndex := 0; higherIndex < lowerIndex; higherIndex++ {
+			higher := updated[higherIndex]
+			if !higher.Due || higher.SupersededBy != "" {
+				continue
+			}
+			if cadenceCovers(config, targets, higher.Cadence, updated[lowerIndex].Cadence) {
+				updated[lowerIndex].SupersededBy = higher.Cadence
+				break
+			}
+		}
+	}
+	return updated
+}
+
+func cadenceCovers(config AppConfig, targets []string, higher string, lower string) bool {
+	translator := pathTranslatorLoader()
+	for _, target := range targets {
+		profile, ok := config.Profiles[target]
+		if !ok {
+			return false
+		}
+
+		higherIncludeKeys := make([]string, 0)
+		for _, includePath := range profile.IncludeByCadence.ForCadence(higher) {
+			if translated, ok := translator.ToWSL(includePath); ok {
+				higherIncludeKeys = append(higherIncludeKeys, translated.Key)
+			}
+		}
+		for _, includePath := range profile.IncludeByCadence.ForCadence(lower) {
+			translated, ok := translator.ToWSL(includePath)
+			if !ok {
+				return false
+			}
+			covered := false
+			for _, higherKey := range higherIncludeKeys {
+				if isSameOrParentPath(higherKey, translated.Key) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return false
+			}
+		}
+
+		lowerExcludes := map[string]struct{}{}
+		for _, excludePath := range profile.ExcludeByCadence.ForCadence(lower) {
+			lowerExcludes[excludePath] = struct{}{}
+		}
+		for _, excludePath := range profile.ExcludeByCadence.ForCadence(higher) {
+			if _, ok := lowerExcludes[excludePath]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func formatCadenceDue(evaluation CadenceDue) string {
+	lastSuccess := "never"
+	if !evaluation.LastSuccess.IsZero() {
+		lastSuccess = evaluation.LastSuccess.Format(time.RFC3339)
+	}
+	switch {
+	case evaluation.SupersededBy != "":
+		return fmt.Sprintf("%s: due, covered by %s (last success %s)", evaluation.Cadence, evaluation.SupersededBy, lastSuccess)
+	case evaluation.Due:
+		return fmt.Sprintf("%s: due (last success %s)", evaluation.Cadence, lastSuccess)
+	default:
+		return fmt.Sprintf("%s: not due (last success %s)", evaluation.Cadence, lastSuccess)
+	}
+}
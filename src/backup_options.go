@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var compressionModes = []string{"auto", "off", "fastest", "better", "max"}
+
+// restic size arguments: a number with an optional k/m/g/t suffix (binary units).
+var resticSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgGtT]?$`)
+
+// extra_args may not take over the repository or the JSON output that run
+// history reads; everything else is passed through as written.
+var managedBackupFlags = []string{"-r", "--repo", "--repository-file", "--json"}
+
+type BackupOptions struct {
+	ExcludeCaches     bool
+	ExcludeIfPresent  []string
+	ExcludeLargerThan string
+	OneFileSystem     bool
+	Compression       string
+	LimitUpload       int
+	LimitDownload     int
+	ReadConcurrency   int
+	PackSize          int
+	IExclude          []string
+	ExtraArgs         []string
+}
+
+type fileBackupOptions struct {
+	ExcludeCaches     bool     `yaml:"exclude_caches"`
+	ExcludeIfPresent  []string `yaml:"exclude_if_present"`
+	ExcludeLargerThan string   `yaml:"exclude_larger_than"`
+	OneFileSystem     bool     `yaml:"one_file_system"`
+	Compression       string   `yaml:"compression"`
+	LimitUpload       int      `yaml:"limit_upload"`
+	LimitDownload     int      `yaml:"limit_download"`
+	ReadConcurrency   int      `yaml:"read_concurrency"`
+	PackSize          int      `yaml:"pack_size"`
+	IExclude          []string `yaml:"iexclude"`
+	ExtraArgs         []string `yaml:"extra_args"`
+}
+
+func loadBackupOptions(file fileBackupOptions, profileName string) (BackupOptions, error) {
+	if file.Compression != "" && !containsValue(compressionModes, file.Compression) {
+		return BackupOptions{}, fmt.Errorf("invalid compression %q (expected %s)", file.Compression, strings.Join(compressionModes, ", "))
+	}
+	if file.ExcludeLargerThan != "" && !resticSizePattern.MatchString(file.ExcludeLargerThan) {
+		return BackupOptions{}, fmt.Errorf("invalid exclude_larger_than %q (expected a size such as 500M or 2G)", file.ExcludeLargerThan)
+	}
+	for name, value := range map[string]int{"limit_upload": file.LimitUpload, "limit_download": file.LimitDownload, "read_concurrency": file.ReadConcurrency, "pack_size": file.PackSize} {
+		if value < 0 {
+			return BackupOptions{}, fmt.Errorf("invalid %s: %d (must not be negative)", name, value)
+		}
+	}
+	// restic refuses --one-file-system on Windows.
+	if file.OneFileSystem && profileName == "windows" {
+		return BackupOptions{}, fmt.Errorf("one_file_system is not supported by restic on Windows")
+	}
+	for _, arg := range file.ExtraArgs {
+		flag, _, _ := strings.Cut(arg, "=")
+		if containsValue(managedBackupFlags, flag) {
+			return BackupOptions{}, fmt.Errorf("extra_args must not set %s", flag)
+		}
+	}
+
+	return BackupOptions{
+		ExcludeCaches:     file.ExcludeCaches,
+		ExcludeIfPresent:  append([]string{}, file.ExcludeIfPresent...),
+		ExcludeLargerThan: file.ExcludeLargerThan,
+		OneFileSystem:     file.OneFileSystem,
+		Compression:       file.Compression,
+		LimitUpload:       file.LimitUpload,
+		LimitDownload:     file.LimitDownload,
+		ReadConcurrency:   file.ReadConcurrency,
+		PackSize:          file.PackSize,
+		IExclude:          append([]string{}, file.IExclude...),
+		ExtraArgs:         append([]string{}, file.ExtraArgs...),
+	}, nil
+}
+
+// Args renders the options as restic backup flags, with extra_args last so
+// they can refine anything above.
+func (options BackupOptions) Args() []string {
+	args := make([]string, 0)
+	if options.ExcludeCaches {
+		args = append(args, "--exclude-caches")
+	}
+	for _, marker := range options.ExcludeIfPresent {
+		args = append(args, "--exclude-if-present", marker)
+	}
+	if options.ExcludeLargerThan != "" {
+		args = append(args, "--exclude-larger-than", options.ExcludeLargerThan)
+	}
+	if options.OneFileSystem {
+		args = append(args, "--one-file-system")
+	}
+	if options.Compression != "" {
+		args = append(args, "--compression", options.Compression)
+	}
+	if options.LimitUpload > 0 {
+		args = append(args, "--limit-upload", strconv.Itoa(options.LimitUpload))
+	}
+	if options.LimitDownload > 0 {
+		args = append(args, "--limit-download", strconv.Itoa(options.LimitDownload))
+	}
+	if options.ReadConcurrency > 0 {
+		args = append(args, "--read-concurrency", strconv.Itoa(options.ReadConcurrency))
+	}
+	if options.PackSize > 0 {
+		args = append(args, "--pack-size", strconv.Itoa(options.PackSize))
+	}
+	for _, pattern := range options.IExclude {
+		args = append(args, "--iexclude", pattern)
+	}
+	return append(args, options.ExtraArgs...)
+}
+
+func containsValue(values []string, candidate string) bool {
+	for _, value := range values {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
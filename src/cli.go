@@ -4,14 +4,35 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Command struct {
-	Name          string
-	Cadence       string
-	Target        string
-	Report        string
+	Name      string
+	Cadence   string
+	Target    string
+	Report    string
+	Action    string
+	Overlap   string
+	DryRun    bool
+	Windows   bool
+	Wait      bool
+	Elevated  bool
+	Profile   string
+	Date      string
+	Snapshot  string
+	Include   string
+	Pattern   string
+	Since     string
+	Hit       int
+	CompareTo string
+	Live      bool
+	InPlace   bool
+	Conflict  string
+	Suffix    string
+	Yes       bool
 }
 
 var runtimeDetector = DetectRuntime
@@ -76,9 +97,19 @@ func validateWSLExecutionContext() error {
 func Usage() string {
 	return strings.Join([]string{
 		"Usage:",
-		"  backup run <daily|weekly|monthly>",
+		"  backup run <daily|weekly|monthly|auto> [--overlap=strict|warn|off|dedupe] [--dry-run] [--wait]",
 		"  backup report <daily|weekly|monthly> [new|excluded]",
-		"  backup restore <target>",
+		"  backup restore <target> [--wait] [--profile=wsl|windows] [--snapshot=<id>] [--include=<path>]",
+		"  backup restore --in-place [--profile=wsl|windows] [--snapshot=<id>] [--include=<path>] [--conflict=skip|overwrite-if-newer|rename|abort] [--suffix=<s>] [--yes] [--wait]",
+		"  backup find <pattern> [--profile=wsl|windows] [--since=YYYY-MM-DD] [--cadence=daily|weekly|monthly] [--restore=<n> --to=<dir>]",
+		"  backup diff <wsl|windows> [snapA] [snapB|--live]",
+		"  backup mount [wsl|windows] <mountpoint> [--cadence=daily|weekly|monthly] [--date=YYYY-MM-DD]",
+		"  backup lint <daily|weekly|monthly>",
+		"  backup config show",
+		"  backup doctor",
+		"  backup schedule <install|remove|status> [--windows [--elevated]]",
+		"  backup digest send",
+		"  backup metrics",
 		"  backup test",
 		"  backup help",
 		"  backup --help",
@@ -87,14 +118,67 @@ func Usage() string {
 		"  new       Show items newly selected for backup (include/exclude diff)",
 		"  excluded  Show items currently excluded from backup",
 		"",
+		"Config layers (lowest to highest precedence):",
+		"  /etc/backup/config.yaml (or BACKUP_SYSTEM_CONFIG)",
+		"  user config (BACKUP_CONFIG, APPDATA, XDG_CONFIG_HOME or ~/.config)",
+		"  config.d/*.yaml next to the user config, in lexical order",
+		"",
 		"Run behavior:",
 		"  WSL-only CLI: run executes both wsl and windows profiles in parallel",
 		"  Platform include overlap is validated in strict mode by default",
+		"  overlap_policy (strict|warn|off|dedupe) in config or --overlap changes that;",
+		"  allowed_overlaps per profile whitelists deliberate shared folders",
+		"  dedupe gives each shared subtree to one profile (overlap_winner: auto|wsl|windows)",
+		"  and excludes it from the other; --dry-run prints the resulting restic commands",
+		"  auto runs only overdue cadences (from run history), widest first; a due",
+		"  monthly or weekly run that captures the same paths covers narrower ones",
+		"",
+		"Locking:",
+		"  run and restore hold a single-instance lock; --wait queues behind a running backup",
+		"",
+		"Mount:",
+		"  mount runs restic mount in the foreground (FUSE, WSL side) and prints the",
+		"  path of the newest snapshot matching --cadence/--date; Ctrl-C unmounts",
+		"",
+		"In-place restore:",
+		"  restore --in-place writes snapshot files back to their original paths and",
+		"  always prints a preview first; nothing changes without --yes. Files that",
+		"  differ locally follow --conflict (default skip; rename restores beside them",
+		"  with --suffix, default .restored). Files it overwrites are backed up first",
+		"  with tag pre-restore",
+		"",
+		"Find:",
+		"  find searches every profile's snapshots in parallel and lists each version of",
+		"  a matching file once; --restore=<n> --to=<dir> restores hit <n> from the newest",
+		"  snapshot holding it",
+		"",
+		"Diff:",
+		"  diff summarizes added/removed/modified files and bytes per include root;",
+		"  with no snapshots it compares the last monthly and last weekly snapshot,",
+		"  one snapshot is compared with the newest, --live compares with the disk",
+		"",
+		"Schedule:",
+		"  install writes systemd user timers for daily/weekly/monthly (Persistent=true)",
+		"  --windows registers Task Scheduler entries running wsl.exe -d <distro> instead",
+		"  (and removes the timers); both load restic credentials from restic.env next",
+		"  to the user config",
+		"  --elevated adds the on-demand task restic.exe uses for VSS when not elevated",
+		"  (vss_elevation: task); registering it needs an elevated shell",
 		"",
 		"As wsl-sys-cli extension:",
-		"  sys backup run <daily|weekly|monthly>",
+		"  sys backup run <daily|weekly|monthly|auto> [--overlap=strict|warn|off|dedupe] [--dry-run] [--wait]",
 		"  sys backup report <daily|weekly|monthly> [new|excluded]",
-		"  sys backup restore <target>",
+		"  sys backup restore <target> [--wait] [--profile=wsl|windows] [--snapshot=<id>] [--include=<path>]",
+		"  sys backup restore --in-place [--profile=wsl|windows] [--snapshot=<id>] [--include=<path>] [--conflict=skip|overwrite-if-newer|rename|abort] [--suffix=<s>] [--yes] [--wait]",
+		"  sys backup find <pattern> [--profile=wsl|windows] [--since=YYYY-MM-DD] [--cadence=daily|weekly|monthly] [--restore=<n> --to=<dir>]",
+		"  sys backup diff <wsl|windows> [snapA] [snapB|--live]",
+		"  sys backup mount [wsl|windows] <mountpoint> [--cadence=daily|weekly|monthly] [--date=YYYY-MM-DD]",
+		"  sys backup lint <daily|weekly|monthly>",
+		"  sys backup config show",
+		"  sys backup doctor",
+		"  sys backup schedule <install|remove|status> [--windows [--elevated]]",
+		"  sys backup digest send",
+		"  sys backup metrics",
 		"  sys backup test",
 		"  sys backup --help",
 	}, "\n")
@@ -127,6 +211,188 @@ func isValidCadence(cadence string) bool {
 	}
 }
 
+func parseRunOptions(command Command, args []string) (Command, error) {
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--overlap="):
+			policy := strings.TrimPrefix(arg, "--overlap=")
+			if !isValidOverlapPolicy(policy) {
+				return Command{}, fmt.Errorf("invalid overlap policy: %s", policy)
+			}
+			command.Overlap = policy
+		case arg == "--dry-run":
+			command.DryRun = true
+		case arg == "--wait":
+			command.Wait = true
+		case strings.HasPrefix(arg, "--"):
+			return Command{}, fmt.Errorf("unknown run option: %s", arg)
+		default:
+			return Command{}, fmt.Errorf("run does not accept options")
+		}
+	}
+	return command, nil
+}
+
+func parseMountArgs(args []string) (Command, error) {
+	parsed := Command{Name: "mount"}
+	positional := make([]string, 0, 2)
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--cadence="):
+			parsed.Cadence = strings.TrimPrefix(arg, "--cadence=")
+			if !isValidCadence(parsed.Cadence) {
+				return Command{}, fmt.Errorf("invalid cadence: %s", parsed.Cadence)
+			}
+		case strings.HasPrefix(arg, "--date="):
+			parsed.Date = strings.TrimPrefix(arg, "--date=")
+		case strings.HasPrefix(arg, "--"):
+			return Command{}, fmt.Errorf("unknown mount option: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	switch len(positional) {
+	case 1:
+		parsed.Target = positional[0]
+	case 2:
+		parsed.Profile, parsed.Target = positional[0], positional[1]
+	default:
+		return Command{}, fmt.Errorf("usage: backup mount [profile] <mountpoint>")
+	}
+	return parsed, nil
+}
+
+func parseRestoreArgs(args []string) (Command, error) {
+	parsed := Command{Name: "restore"}
+	for _, option := range args {
+		switch {
+		case option == "--wait":
+			parsed.Wait = true
+		case option == "--in-place":
+			parsed.InPlace = true
+		case option == "--yes":
+			parsed.Yes = true
+		case strings.HasPrefix(option, "--profile="):
+			parsed.Profile = strings.TrimPrefix(option, "--profile=")
+		case strings.HasPrefix(option, "--snapshot="):
+			parsed.Snapshot = strings.TrimPrefix(option, "--snapshot=")
+		case strings.HasPrefix(option, "--include="):
+			parsed.Include = strings.TrimPrefix(option, "--include=")
+		case strings.HasPrefix(option, "--conflict="):
+			parsed.Conflict = strings.TrimPrefix(option, "--conflict=")
+			if !containsValue(conflictPolicies, parsed.Conflict) {
+				return Command{}, fmt.Errorf("invalid conflict policy: %s (expected %s)", parsed.Conflict, strings.Join(conflictPolicies, ", "))
+			}
+		case strings.HasPrefix(option, "--suffix="):
+			parsed.Suffix = strings.TrimPrefix(option, "--suffix=")
+			if parsed.Suffix == "" {
+				return Command{}, fmt.Errorf("--suffix must not be empty")
+			}
+		case parsed.Target == "" && !strings.HasPrefix(option, "--"):
+			parsed.Target = option
+		default:
+			return Command{}, fmt.Errorf("unknown restore option: %s", option)
+		}
+	}
+	if parsed.Profile != "" && parsed.Profile != "wsl" && parsed.Profile != "windows" {
+		return Command{}, fmt.Errorf("invalid profile: %s", parsed.Profile)
+	}
+	if parsed.InPlace {
+		if parsed.Target != "" {
+			return Command{}, fmt.Errorf("--in-place restores to the original paths and takes no target")
+		}
+		return parsed, nil
+	}
+	if parsed.Target == "" {
+		return Command{}, fmt.Errorf("missing target")
+	}
+	if parsed.Conflict != "" || parsed.Suffix != "" || parsed.Yes {
+		return Command{}, fmt.Errorf("--conflict, --suffix and --yes require --in-place")
+	}
+	return parsed, nil
+}
+
+func parseFindArgs(args []string) (Command, error) {
+	parsed := Command{Name: "find"}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--profile="):
+			parsed.Profile = strings.TrimPrefix(arg, "--profile=")
+			if parsed.Profile != "wsl" && parsed.Profile != "windows" {
+				return Command{}, fmt.Errorf("invalid profile: %s", parsed.Profile)
+			}
+		case strings.HasPrefix(arg, "--since="):
+			parsed.Since = strings.TrimPrefix(arg, "--since=")
+			if _, err := time.Parse("2006-01-02", parsed.Since); err != nil {
+				return Command{}, fmt.Errorf("invalid --since date: %s (expected YYYY-MM-DD)", parsed.Since)
+			}
+		case strings.HasPrefix(arg, "--cadence="):
+			parsed.Cadence = strings.TrimPrefix(arg, "--cadence=")
+			if !isValidCadence(parsed.Cadence) {
+				return Command{}, fmt.Errorf("invalid cadence: %s", parsed.Cadence)
+			}
+		case strings.HasPrefix(arg, "--restore="):
+			hit, err := strconv.Atoi(strings.TrimPrefix(arg, "--restore="))
+			if err != nil || hit < 1 {
+				return Command{}, fmt.Errorf("invalid --restore hit: %s", strings.TrimPrefix(arg, "--restore="))
+			}
+			parsed.Hit = hit
+		case strings.HasPrefix(arg, "--to="):
+			parsed.Target = strings.TrimPrefix(arg, "--to=")
+		case strings.HasPrefix(arg, "--"):
+			return Command{}, fmt.Errorf("unknown find option: %s", arg)
+		default:
+			if parsed.Pattern != "" {
+				return Command{}, fmt.Errorf("find accepts a single pattern")
+			}
+			parsed.Pattern = arg
+		}
+	}
+	if parsed.Pattern == "" {
+		return Command{}, fmt.Errorf("missing find pattern")
+	}
+	if (parsed.Hit > 0) != (parsed.Target != "") {
+		return Command{}, fmt.Errorf("--restore and --to must be given together")
+	}
+	return parsed, nil
+}
+
+func parseDiffArgs(args []string) (Command, error) {
+	parsed := Command{Name: "diff"}
+	positional := make([]string, 0, 3)
+	for _, arg := range args {
+		switch {
+		case arg == "--live":
+			parsed.Live = true
+		case strings.HasPrefix(arg, "--"):
+			return Command{}, fmt.Errorf("unknown diff option: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) == 0 {
+		return Command{}, fmt.Errorf("missing profile")
+	}
+	parsed.Profile = positional[0]
+	if parsed.Profile != "wsl" && parsed.Profile != "windows" {
+		return Command{}, fmt.Errorf("invalid profile: %s", parsed.Profile)
+	}
+	maxSnapshots := 2
+	if parsed.Live {
+		maxSnapshots = 1
+	}
+	if len(positional)-1 > maxSnapshots {
+		return Command{}, fmt.Errorf("usage: backup diff <profile> [snapA] [snapB|--live]")
+	}
+	if len(positional) > 1 {
+		parsed.Snapshot = positional[1]
+	}
+	if len(positional) > 2 {
+		parsed.CompareTo = positional[2]
+	}
+	return parsed, nil
+}
+
 func ParseArgs(args []string) (Command, error) {
 	if len(args) == 0 {
 		return Command{Name: "help"}, nil
@@ -136,20 +402,28 @@ func ParseArgs(args []string) (Command, error) {
 	switch command {
 	case "help", "-h", "--help":
 		return Command{Name: "help"}, nil
+	case "lint":
+		if len(args) < 2 {
+			return Command{}, fmt.Errorf("missing cadence")
+		}
+		if !isValidCadence(args[1]) {
+			return Command{}, fmt.Errorf("invalid cadence: %s", args[1])
+		}
+		if len(args) > 2 {
+			return Command{}, fmt.Errorf("lint does not accept options")
+		}
+		return Command{Name: command, Cadence: args[1]}, nil
 	case "run", "report":
 		if len(args) < 2 {
 			return Command{}, fmt.Errorf("missing cadence")
 		}
 		cadence := args[1]
-		if !isValidCadence(cadence) {
+		if !isValidCadence(cadence) && !(command == "run" && cadence == "auto") {
 			return Command{}, fmt.Errorf("invalid cadence: %s", cadence)
 		}
 
 		if command == "run" {
-			if len(args) > 2 {
-				return Command{}, fmt.Errorf("run does not accept options")
-			}
-			return Command{Name: command, Cadence: cadence}, nil
+			return parseRunOptions(Command{Name: command, Cadence: cadence}, args[2:])
 		}
 
 		reportOption, err := parseReportOption(args[2:])
@@ -158,13 +432,69 @@ func ParseArgs(args []string) (Command, error) {
 		}
 		return Command{Name: command, Cadence: cadence, Report: reportOption}, nil
 	case "restore":
+		return parseRestoreArgs(args[1:])
+	case "mount":
+		return parseMountArgs(args[1:])
+	case "find":
+		return parseFindArgs(args[1:])
+	case "diff":
+		return parseDiffArgs(args[1:])
+	case "config":
 		if len(args) < 2 {
-			return Command{}, fmt.Errorf("missing target")
+			return Command{}, fmt.Errorf("missing config action")
+		}
+		if args[1] != "show" {
+			return Command{}, fmt.Errorf("unknown config action: %s", args[1])
 		}
 		if len(args) > 2 {
-			return Command{}, fmt.Errorf("restore does not accept options")
+			return Command{}, fmt.Errorf("config show does not accept options")
+		}
+		return Command{Name: command, Action: args[1]}, nil
+	case "doctor":
+		if len(args) > 1 {
+			return Command{}, fmt.Errorf("doctor does not accept options")
 		}
-		return Command{Name: command, Target: args[1]}, nil
+		return Command{Name: command}, nil
+	case "schedule":
+		if len(args) < 2 {
+			return Command{}, fmt.Errorf("missing schedule action")
+		}
+		switch args[1] {
+		case "install", "remove", "status":
+		default:
+			return Command{}, fmt.Errorf("unknown schedule action: %s", args[1])
+		}
+		parsed := Command{Name: command, Action: args[1]}
+		for _, option := range args[2:] {
+			switch option {
+			case "--windows":
+				parsed.Windows = true
+			case "--elevated":
+				parsed.Elevated = true
+			default:
+				return Command{}, fmt.Errorf("unknown schedule option: %s", option)
+			}
+		}
+		if parsed.Elevated && !parsed.Windows {
+			return Command{}, fmt.Errorf("--elevated requires --windows")
+		}
+		return parsed, nil
+	case "metrics":
+		if len(args) > 1 {
+			return Command{}, fmt.Errorf("metrics does not accept options")
+		}
+		return Command{Name: command}, nil
+	case "digest":
+		if len(args) < 2 {
+			return Command{}, fmt.Errorf("missing digest action")
+		}
+		if args[1] != "send" {
+			return Command{}, fmt.Errorf("unknown digest action: %s", args[1])
+		}
+		if len(args) > 2 {
+			return Command{}, fmt.Errorf("digest send does not accept options")
+		}
+		return Command{Name: command, Action: args[1]}, nil
 	case "test":
 		if len(args) > 1 {
 			return Command{}, fmt.Errorf("test does not accept options")
@@ -180,59 +510,96 @@ func Run(command Command, executor Executor) (string, error) {
 	case "help":
 		return Usage(), nil
 	case "run":
+		return runBackup(command, executor)
+	case "report":
 		if err := validateWSLExecutionContext(); err != nil {
 			return "", err
 		}
-		platform := runtimeDetector()
-		plan, err := BuildRunPlan(command.Cadence, platform)
+		switch command.Report {
+		case "new":
+			return fmt.Sprintf("%s backup report (new) is not implemented yet.", command.Cadence), nil
+		case "excluded":
+			platform := runtimeDetector()
+			plan, err := BuildRunPlan(command.Cadence, platform)
+			if err != nil {
+				return "", err
+			}
+			config, err := LoadConfig(platform)
+			if err != nil {
+				return "", err
+			}
+			if err := ValidatePlanConfig(plan, config); err != nil {
+				return "", err
+			}
+			return BuildExcludedReport(plan, config)
+		default:
+			return fmt.Sprintf("%s backup report is not implemented yet.", command.Cadence), nil
+		}
+	case "restore":
+		return runRestore(command, executor)
+	case "find":
+		return runFind(command, executor)
+	case "diff":
+		if err := validateWSLExecutionContext(); err != nil {
+			return "", err
+		}
+		config, err := LoadConfig(runtimeDetector())
 		if err != nil {
 			return "", err
 		}
-		config, err := LoadConfig(platform)
+		if !config.Exists {
+			return "", fmt.Errorf("diff requires config file at: %s", config.Path)
+		}
+		return RunDiff(command, config, executor)
+	case "mount":
+		if err := validateWSLExecutionContext(); err != nil {
+			return "", err
+		}
+		config, err := LoadConfig(runtimeDetector())
 		if err != nil {
 			return "", err
 		}
-		if err := ValidatePlanConfig(plan, config); err != nil {
+		if !config.Exists {
+			return "", fmt.Errorf("mount requires config file at: %s", config.Path)
+		}
+		return RunMount(command, config, executor, os.Stdout)
+	case "lint":
+		if err := validateWSLExecutionContext(); err != nil {
 			return "", err
 		}
-		warnings := FindPlatformIncludeOverlapWarnings(plan, config)
-		if len(warnings) > 0 {
-			return "", fmt.Errorf("platform include overlap detected in strict mode\n%s", strings.Join(warnings, "\n"))
+		platform := runtimeDetector()
+		plan, err := BuildRunPlan(command.Cadence, platform)
+		if err != nil {
+			return "", err
 		}
-		invocations, err := BuildResticInvocations(plan, config)
+		config, err := LoadConfig(platform)
 		if err != nil {
 			return "", err
 		}
-		if command.Cadence == "daily" && config.Exists {
-			results, err := ExecuteResticInvocations(invocations, executor)
-			if err != nil {
-				return "", err
-			}
-			outputLines := []string{fmt.Sprintf("daily backup run executed for platforms=%s (steps=%d).", strings.Join(plan.Targets, ","), len(results))}
-			outputLines = append(outputLines, warnings...)
-			return strings.Join(outputLines, "\n"), nil
+		if err := ValidatePlanConfig(plan, config); err != nil {
+			return "", err
 		}
-		outputLines := []string{fmt.Sprintf("scaffold only: %s backup run platforms=%s (execution not implemented yet).", plan.Cadence, strings.Join(plan.Targets, ","))}
-		outputLines = append(outputLines, warnings...)
-		return strings.Join(outputLines, "\n"), nil
-	case "report":
+		findings := LintRules(plan, config)
+		report := FormatRuleFindings(findings)
+		if errorCount := countRuleFindings(findings, SeverityError); errorCount > 0 {
+			return "", fmt.Errorf("%s\nlint: %d error(s)", report, errorCount)
+		}
+		return report, nil
+	case "config":
 		if err := validateWSLExecutionContext(); err != nil {
 			return "", err
 		}
-		switch command.Report {
-		case "new":
-			return fmt.Sprintf("%s backup report (new) is not implemented yet.", command.Cadence), nil
-		case "excluded":
-			return fmt.Sprintf("%s backup report (excluded) is not implemented yet.", command.Cadence), nil
-		default:
-			return fmt.Sprintf("%s backup report is not implemented yet.", command.Cadence), nil
+		config, err := LoadConfig(runtimeDetector())
+		if err != nil {
+			return "", err
 		}
-	case "restore":
+		return BuildConfigShowReport(config), nil
+	case "doctor":
 		if err := validateWSLExecutionContext(); err != nil {
 			return "", err
 		}
 		platform := runtimeDetector()
-		plan, err := BuildRestorePlan(platform, command.Target)
+		plan, err := BuildRunPlan("daily", platform)
 		if err != nil {
 			return "", err
 		}
@@ -240,18 +607,68 @@ func Run(command Command, executor Executor) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		if !config.Exists {
-			return "", fmt.Errorf("restore requires config file at: %s", config.Path)
+		checks := RunDoctorChecks(plan.Targets, config, executor)
+		report := FormatDoctorReport(checks)
+		failures := countDoctorStatus(checks, DoctorFail)
+		summary := fmt.Sprintf("doctor: %d passed, %d warnings, %d failed", countDoctorStatus(checks, DoctorPass), countDoctorStatus(checks, DoctorWarn), failures)
+		if failures > 0 {
+			return "", fmt.Errorf("%s\n%s", report, summary)
+		}
+		return report + "\n" + summary, nil
+	case "schedule":
+		if err := validateWSLExecutionContext(); err != nil {
+			return "", err
+		}
+		var output string
+		var err error
+		switch command.Action {
+		case "install":
+			output, err = InstallSchedule(command.Windows, executor)
+		case "remove":
+			output, err = RemoveSchedule(command.Windows, executor)
+		default:
+			output, err = ScheduleStatus(command.Windows, executor)
+		}
+		if err != nil || !command.Elevated {
+			return output, err
+		}
+		switch command.Action {
+		case "install":
+			config, err := LoadConfig(runtimeDetector())
+			if err != nil {
+				return "", fmt.Errorf("%s\n%w", output, err)
+			}
+			line, err := InstallElevatedTask(config, executor)
+			if err != nil {
+				return "", fmt.Errorf("%s\n%w", output, err)
+			}
+			return joinLines([]string{output, line}), nil
+		case "remove":
+			return joinLines([]string{output, RemoveElevatedTask(executor)}), nil
+		default:
+			return joinLines([]string{output, "elevated task:", ElevatedTaskStatus(executor)}), nil
 		}
-		invocation, err := BuildRestoreInvocation(plan, config)
+	case "metrics":
+		if err := validateWSLExecutionContext(); err != nil {
+			return "", err
+		}
+		records, err := LoadRunHistory()
 		if err != nil {
 			return "", err
 		}
-		results, err := ExecuteResticInvocations([]ResticInvocation{invocation}, executor)
+		return strings.TrimSuffix(FormatPrometheusMetrics(records), "\n"), nil
+	case "digest":
+		if err := validateWSLExecutionContext(); err != nil {
+			return "", err
+		}
+		config, err := LoadConfig(runtimeDetector())
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf("restore executed for target=%s (steps=%d).", plan.Target, len(results)), nil
+		if _, err := SendDigest(config.Notifications.Email, true, executor); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("email digest sent to %s", strings.Join(config.Notifications.Email.To, ", ")), nil
 	case "test":
 		if err := validateWSLExecutionContext(); err != nil {
 			return "", err
@@ -265,6 +682,300 @@ func Run(command Command, executor Executor) (string, error) {
 	}
 }
 
+func runBackup(command Command, executor Executor) (string, error) {
+	if err := validateWSLExecutionContext(); err != nil {
+		return "", err
+	}
+	platform := runtimeDetector()
+	config, err := LoadConfig(platform)
+	if err != nil {
+		return "", err
+	}
+	if !command.DryRun {
+		lock, err := AcquireInstanceLock("run "+command.Cadence, command.Wait)
+		if err != nil {
+			return "", err
+		}
+		defer lock.Release()
+	}
+	if command.Cadence == "auto" {
+		output, err := runAutoBackup(command, platform, config, executor)
+		return appendDigestLines(command, config, output, err, executor)
+	}
+	outcome, err := runCadence(command, command.Cadence, platform, config, executor)
+	if err != nil {
+		return "", err
+	}
+	if outcome.err != nil {
+		if len(outcome.lines) > 0 {
+			return appendDigestLines(command, config, "", fmt.Errorf("%w\n%s", outcome.err, joinLines(outcome.lines)), executor)
+		}
+		return appendDigestLines(command, config, "", outcome.err, executor)
+	}
+	return appendDigestLines(command, config, joinLines(outcome.lines), nil, executor)
+}
+
+// appendDigestLines sends the email digest when it is due after a real run,
+// whether or not the run succeeded, and reports the result alongside it.
+func appendDigestLines(command Command, config AppConfig, output string, runErr error, executor Executor) (string, error) {
+	if command.DryRun || !config.Exists || !config.Notifications.Email.Enabled() {
+		return output, runErr
+	}
+	line := ""
+	sent, err := SendDigest(config.Notifications.Email, false, executor)
+	switch {
+	case err != nil:
+		line = fmt.Sprintf("warning: email digest failed: %v", err)
+	case sent:
+		line = fmt.Sprintf("email digest sent to %s", strings.Join(config.Notifications.Email.To, ", "))
+	default:
+		return output, runErr
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("%w\n%s", runErr, line)
+	}
+	return joinLines([]string{output, line}), nil
+}
+
+type cadenceOutcome struct {
+	lines    []string
+	executed bool
+	err      error
+}
+
+// runCadence returns setup problems as an error and a failed restic
+// invocation in outcome.err, after the run has been recorded in history.
+func runCadence(command Command, cadence string, platform Runtime, config AppConfig, executor Executor) (cadenceOutcome, error) {
+	plan, err := BuildRunPlan(cadence, platform)
+	if err != nil {
+		return cadenceOutcome{}, err
+	}
+	if err := ValidatePlanConfig(plan, config); err != nil {
+		return cadenceOutcome{}, err
+	}
+	plan, warnings, err := applyOverlapPolicy(ResolveOverlapPolicy(command.Overlap, config), plan, config)
+	if err != nil {
+		return cadenceOutcome{}, err
+	}
+	invocations, err := BuildResticInvocations(plan, config)
+	if err != nil {
+		return cadenceOutcome{}, err
+	}
+	if command.DryRun {
+		outputLines := []string{fmt.Sprintf("dry run: %s backup run platforms=%s (steps=%d).", plan.Cadence, strings.Join(plan.Targets, ","), len(invocations))}
+		for _, invocation := range invocations {
+			outputLines = append(outputLines, fmt.Sprintf("  %s: %s %s", invocation.Target, invocation.Executable, strings.Join(invocation.Args, " ")))
+			if needsRuleListFiles(invocation) {
+				outputLines = append(outputLines, fmt.Sprintf("  %s: includes and excludes will be passed through --files-from-verbatim and --exclude-file list files", invocation.Target))
+			}
+		}
+		for _, assignment := range plan.Ownership {
+			outputLines = append(outputLines, "  "+formatOverlapAssignment(assignment))
+		}
+		outputLines = append(outputLines, warnings...)
+		return cadenceOutcome{lines: outputLines}, nil
+	}
+	if !config.Exists {
+		outputLines := []string{fmt.Sprintf("skipped: %s backup run platforms=%s (no config file at %s).", plan.Cadence, strings.Join(plan.Targets, ","), config.Path)}
+		outputLines = append(outputLines, warnings...)
+		return cadenceOutcome{lines: outputLines}, nil
+	}
+	startedAt := clock()
+	if err := PreflightResticVersions(invocations, config, executor); err != nil {
+		failed := make([]ExecutionResult, 0, len(plan.Targets))
+		for _, target := range plan.Targets {
+			failed = append(failed, ExecutionResult{Target: target, Err: err})
+		}
+		setupWarnings := make([]string, 0)
+		if historyErr := AppendRunHistory(runRecordsFromResults(plan.Cadence, startedAt, failed)); historyErr != nil {
+			setupWarnings = append(setupWarnings, fmt.Sprintf("warning: could not record run history: %v", historyErr))
+		}
+		if metricsErr := updateMetricsTextfile(config.Metrics); metricsErr != nil {
+			setupWarnings = append(setupWarnings, fmt.Sprintf("warning: could not write metrics textfile: %v", metricsErr))
+		}
+		summary := BuildRunSummary(plan.Cadence, startedAt, clock(), nil, append(warnings, setupWarnings...))
+		summary.Status = RunStatusFailure
+		summary.Error = err.Error()
+		setupWarnings = append(setupWarnings, SendRunNotifications(config.Notifications, summary, executor)...)
+		if len(setupWarnings) > 0 {
+			return cadenceOutcome{}, fmt.Errorf("%w\n%s", err, joinLines(setupWarnings))
+		}
+		return cadenceOutcome{}, err
+	}
+
+	invocations, cleanupRuleLists, err := SpillRuleLists(invocations)
+	if err != nil {
+		return cadenceOutcome{}, err
+	}
+	results := ExecuteResticInvocationsWithHooks(invocations, plan.Cadence, config, executor)
+	cleanupRuleLists()
+	for _, result := range results {
+		warnings = append(warnings, result.Warnings...)
+	}
+	if err := AppendRunHistory(runRecordsFromResults(plan.Cadence, startedAt, results)); err != nil {
+		warnings = append(warnings, fmt.Sprintf("warning: could not record run history: %v", err))
+	}
+	if err := updateMetricsTextfile(config.Metrics); err != nil {
+		warnings = append(warnings, fmt.Sprintf("warning: could not write metrics textfile: %v", err))
+	}
+	summary := BuildRunSummary(plan.Cadence, startedAt, clock(), results, warnings)
+	warnings = append(warnings, SendRunNotifications(config.Notifications, summary, executor)...)
+	if err := firstExecutionError(results); err != nil {
+		return cadenceOutcome{lines: warnings, executed: true, err: err}, nil
+	}
+	outputLines := []string{fmt.Sprintf("%s backup run executed for platforms=%s (steps=%d).", plan.Cadence, strings.Join(plan.Targets, ","), len(results))}
+	outputLines = append(outputLines, warnings...)
+	return cadenceOutcome{lines: outputLines, executed: true}, nil
+}
+
+func runAutoBackup(command Command, platform Runtime, config AppConfig, executor Executor) (string, error) {
+	plan, err := BuildRunPlan("daily", platform)
+	if err != nil {
+		return "", err
+	}
+	history, err := LoadRunHistory()
+	if err != nil {
+		return "", err
+	}
+	evaluations := ApplyCadenceSupersession(EvaluateDueCadences(history, plan.Targets, clock()), plan.Targets, config)
+
+	outputLines := []string{"run auto:"}
+	for _, evaluation := range evaluations {
+		outputLines = append(outputLines, "  "+formatCadenceDue(evaluation))
+	}
+
+	succeeded := map[string]bool{}
+	failures := make([]string, 0)
+	ranAny := false
+	for _, evaluation := range evaluations {
+		if !evaluation.Due {
+			continue
+		}
+		if evaluation.SupersededBy != "" && succeeded[evaluation.SupersededBy] {
+			if !command.DryRun {
+				now := clock()
+				records := make([]RunRecord, 0, len(plan.Targets))
+				for _, target := range plan.Targets {
+					records = append(records, RunRecord{Cadence: evaluation.Cadence, Profile: target, StartedAt: now, FinishedAt: now, Success: true, SupersededBy: evaluation.SupersededBy})
+				}
+				if err := AppendRunHistory(records); err != nil {
+					outputLines = append(outputLines, fmt.Sprintf("warning: could not record run history: %v", err))
+				}
+			}
+			outputLines = append(outputLines, fmt.Sprintf("%s backup run covered by %s run.", evaluation.Cadence, evaluation.SupersededBy))
+			succeeded[evaluation.Cadence] = true
+			continue
+		}
+
+		ranAny = true
+		outcome, err := runCadence(command, evaluation.Cadence, platform, config, executor)
+		if err != nil {
+			return "", err
+		}
+		outputLines = append(outputLines, outcome.lines...)
+		if outcome.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", evaluation.Cadence, outcome.err))
+			continue
+		}
+		succeeded[evaluation.Cadence] = outcome.executed || command.DryRun
+	}
+	if !ranAny && len(succeeded) == 0 {
+		outputLines = append(outputLines, "nothing due.")
+	}
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%s\n%s", joinLines(outputLines), joinLines(failures))
+	}
+	return joinLines(outputLines), nil
+}
+
+func runRestore(command Command, executor Executor) (string, error) {
+	if err := validateWSLExecutionContext(); err != nil {
+		return "", err
+	}
+	if command.InPlace {
+		config, err := LoadConfig(runtimeDetector())
+		if err != nil {
+			return "", err
+		}
+		if !config.Exists {
+			return "", fmt.Errorf("restore requires config file at: %s", config.Path)
+		}
+		return RunInPlaceRestore(command, config, executor, os.Stdout)
+	}
+	platform := runtimeDetector()
+	plan, err := BuildRestorePlan(platform, command.Target)
+	if err != nil {
+		return "", err
+	}
+	if command.Profile != "" {
+		plan.Target = command.Profile
+	}
+	plan.Snapshot = command.Snapshot
+	plan.Include = command.Include
+	config, err := LoadConfig(platform)
+	if err != nil {
+		return "", err
+	}
+	if !config.Exists {
+		return "", fmt.Errorf("restore requires config file at: %s", config.Path)
+	}
+	lock, err := AcquireInstanceLock("restore "+command.Target, command.Wait)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Release()
+	invocation, err := BuildRestoreInvocation(plan, config)
+	if err != nil {
+		return "", err
+	}
+	if err := PreflightResticVersions([]ResticInvocation{invocation}, config, executor); err != nil {
+		return "", err
+	}
+	results, err := ExecuteResticInvocations([]ResticInvocation{invocation}, executor)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("restore executed for target=%s (steps=%d).", plan.Target, len(results)), nil
+}
+
+func runFind(command Command, executor Executor) (string, error) {
+	if err := validateWSLExecutionContext(); err != nil {
+		return "", err
+	}
+	config, err := LoadConfig(runtimeDetector())
+	if err != nil {
+		return "", err
+	}
+	if !config.Exists {
+		return "", fmt.Errorf("find requires config file at: %s", config.Path)
+	}
+	profiles := []string{"wsl", "windows"}
+	if command.Profile != "" {
+		profiles = []string{command.Profile}
+	}
+	hits, warnings, err := FindAcrossProfiles(profiles, command.Pattern, FindFilter{Cadence: command.Cadence, Since: command.Since}, config, executor)
+	if err != nil {
+		return "", err
+	}
+	if command.Hit > 0 {
+		if command.Hit > len(hits) {
+			return "", fmt.Errorf("no find hit [%d] (%d hits for %q)", command.Hit, len(hits), command.Pattern)
+		}
+		return runRestore(RestoreCommandForHit(hits[command.Hit-1], command.Target), executor)
+	}
+	lines := append([]string{}, warnings...)
+	if len(hits) == 0 {
+		return joinLines(append(lines, fmt.Sprintf("no matches for %q", command.Pattern))), nil
+	}
+	lines = append(lines, FormatFindHits(hits)...)
+	lines = append(lines, FormatFindRestoreHint(command))
+	return joinLines(lines), nil
+}
+
+func joinLines(lines []string) string {
+	return strings.Join(lines, "\n")
+}
+
 func RunCLI(args []string, stdout io.Writer, stderr io.Writer, executor Executor) int {
 	command, err := ParseArgs(args)
 	if err != nil {
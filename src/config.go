@@ -57,6 +57,19 @@ func (paths CadencePaths) ForCadence(cadence string) []string {
 	}
 }
 
+func (files CadencePathFiles) ForCadence(cadence string) string {
+	switch cadence {
+	case "daily":
+		return files.Daily
+	case "weekly":
+		return files.Weekly
+	case "monthly":
+		return files.Monthly
+	default:
+		return ""
+	}
+}
+
 func (paths *CadencePaths) setAll(values []string) {
 	paths.Daily = append([]string{}, values...)
 	paths.Weekly = append([]string{}, values...)
@@ -85,30 +98,84 @@ func (paths *CadencePaths) UnmarshalYAML(node *yaml.Node) error {
 	}
 }
 
+var cadences = []string{"daily", "weekly", "monthly"}
+
+const (
+	RuleKindInclude = "include"
+	RuleKindExclude = "exclude"
+
+	RuleOriginInline = "inline"
+	RuleOriginFile   = "file"
+	RuleOriginPreset = "preset"
+)
+
+type RuleEntry struct {
+	Kind    string
+	Cadence string
+	Path    string
+	Origin  string
+	Source  string
+}
+
 type ProfileConfig struct {
 	IncludeByCadence CadencePaths
 	ExcludeByCadence CadencePaths
+	Rules            []RuleEntry
+	ExcludePresets   []string
 	UseFSSnapshot    bool
 	RepositoryHint   string
+	ResticPath       string
+	AllowedOverlaps  []string
+	Hooks            HookConfig
+	Tags             []string
+	Host             string
+	HostShortName    bool
+	HostLowercase    bool
+	BackupOptions    BackupOptions
+	VSSElevation     string
 }
 
 type AppConfig struct {
-	Path     string
-	Exists   bool
-	Profiles map[string]ProfileConfig
+	Path             string
+	Exists           bool
+	Profiles         map[string]ProfileConfig
+	Layers           []string
+	Sources          map[string]string
+	ResticVersion    string
+	ResticMinVersion string
+	OverlapPolicy    string
+	OverlapWinner    string
+	Notifications    NotificationConfig
+	Metrics          MetricsConfig
 }
 
 type fileProfileConfig struct {
-	Repository    string           `yaml:"repository"`
-	IncludePaths  CadencePaths     `yaml:"include"`
-	ExcludePaths  CadencePaths     `yaml:"exclude"`
-	IncludeFiles  CadencePathFiles `yaml:"include_files"`
-	ExcludeFiles  CadencePathFiles `yaml:"exclude_files"`
-	UseFSSnapshot bool             `yaml:"use_fs_snapshot"`
+	Repository      string            `yaml:"repository"`
+	IncludePaths    CadencePaths      `yaml:"include"`
+	ExcludePaths    CadencePaths      `yaml:"exclude"`
+	IncludeFiles    CadencePathFiles  `yaml:"include_files"`
+	ExcludeFiles    CadencePathFiles  `yaml:"exclude_files"`
+	ExcludePresets  []string          `yaml:"exclude_presets"`
+	UseFSSnapshot   bool              `yaml:"use_fs_snapshot"`
+	ResticPath      string            `yaml:"restic_path"`
+	AllowedOverlaps []string          `yaml:"allowed_overlaps"`
+	Hooks           fileHookConfig    `yaml:"hooks"`
+	Tags            []string          `yaml:"tags"`
+	Host            string            `yaml:"host"`
+	HostShortName   bool              `yaml:"host_short_name"`
+	HostLowercase   bool              `yaml:"host_lowercase"`
+	BackupOptions   fileBackupOptions `yaml:"backup_options"`
+	VSSElevation    string            `yaml:"vss_elevation"`
 }
 
 type fileAppConfig struct {
-	Profiles map[string]fileProfileConfig `yaml:"profiles"`
+	Profiles         map[string]fileProfileConfig `yaml:"profiles"`
+	ResticVersion    string                       `yaml:"restic_version"`
+	ResticMinVersion string                       `yaml:"restic_min_version"`
+	OverlapPolicy    string                       `yaml:"overlap_policy"`
+	OverlapWinner    string                       `yaml:"overlap_winner"`
+	Notifications    fileNotificationConfig       `yaml:"notifications"`
+	Metrics          fileMetricsConfig            `yaml:"metrics"`
 }
 
 func ResolveConfigPath(runtime Runtime) (string, error) {
@@ -161,81 +228,210 @@ func LoadConfig(runtime Runtime) (AppConfig, error) {
 		return AppConfig{}, err
 	}
 
-	config := defaultConfig(path)
-	if _, err := os.Stat(path); err == nil {
-		data, readErr := os.ReadFile(path)
+	layers, err := ResolveConfigLayers(runtime)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	merge := newConfigLayerMerge()
+	for _, layerPath := range layers {
+		data, readErr := os.ReadFile(layerPath)
 		if readErr != nil {
+			if os.IsNotExist(readErr) {
+				continue
+			}
 			return AppConfig{}, fmt.Errorf("read config: %w", readErr)
 		}
+		if applyErr := merge.apply(layerPath, data); applyErr != nil {
+			return AppConfig{}, applyErr
+		}
+	}
+
+	if len(merge.appliedLayers) == 0 {
+		return defaultConfig(path), nil
+	}
+
+	var parsed fileAppConfig
+	if decodeErr := merge.decode(&parsed); decodeErr != nil {
+		return AppConfig{}, decodeErr
+	}
+
+	loadedProfiles := map[string]ProfileConfig{}
+	configDir := filepath.Dir(path)
+	ruleFileDir := func(profileName string, field string) string {
+		if source, ok := merge.sources[profileSourceKey(profileName, field)]; ok {
+			return filepath.Dir(source)
+		}
+		return configDir
+	}
+	for profileName, profile := range parsed.Profiles {
+		includeFiles := withCadencePathFileDefaults(profile.IncludeFiles, defaultCadencePathFiles(profileName, "include"))
+		excludeFiles := withCadencePathFileDefaults(profile.ExcludeFiles, defaultCadencePathFiles(profileName, "exclude"))
 
-		var parsed fileAppConfig
-		if unmarshalErr := yaml.Unmarshal(data, &parsed); unmarshalErr != nil {
-			return AppConfig{}, fmt.Errorf("parse config: %w", unmarshalErr)
+		includeFromFiles, loadIncludeErr := loadCadenceRuleFiles(includeFiles, ruleFileDir(profileName, "include_files"), RuleKindInclude)
+		if loadIncludeErr != nil {
+			return AppConfig{}, fmt.Errorf("load include files for profile %s: %w", profileName, loadIncludeErr)
 		}
 
-		loadedProfiles := map[string]ProfileConfig{}
-		configDir := filepath.Dir(path)
-		for profileName, profile := range parsed.Profiles {
-			includeFiles := withCadencePathFileDefaults(profile.IncludeFiles, defaultCadencePathFiles(profileName, "include"))
-			excludeFiles := withCadencePathFileDefaults(profile.ExcludeFiles, defaultCadencePathFiles(profileName, "exclude"))
+		excludeFromFiles, loadExcludeErr := loadCadenceRuleFiles(excludeFiles, ruleFileDir(profileName, "exclude_files"), RuleKindExclude)
+		if loadExcludeErr != nil {
+			return AppConfig{}, fmt.Errorf("load exclude files for profile %s: %w", profileName, loadExcludeErr)
+		}
 
-			includeFromFiles, loadIncludeErr := loadCadencePathsFromFiles(includeFiles, configDir)
-			if loadIncludeErr != nil {
-				return AppConfig{}, fmt.Errorf("load include files for profile %s: %w", profileName, loadIncludeErr)
+		presetRules := make([]RuleEntry, 0)
+		for _, presetName := range profile.ExcludePresets {
+			patterns, ok := ExcludePresetPatterns(presetName)
+			if !ok {
+				return AppConfig{}, fmt.Errorf("load exclude presets for profile %s: unknown exclude preset: %s", profileName, presetName)
 			}
-
-			excludeFromFiles, loadExcludeErr := loadCadencePathsFromFiles(excludeFiles, configDir)
-			if loadExcludeErr != nil {
-				return AppConfig{}, fmt.Errorf("load exclude files for profile %s: %w", profileName, loadExcludeErr)
+			for _, cadence := range cadences {
+				for _, pattern := range patterns {
+					presetRules = append(presetRules, RuleEntry{Kind: RuleKindExclude, Cadence: cadence, Path: pattern, Origin: RuleOriginPreset, Source: presetName})
+				}
 			}
+		}
 
-			loadedProfiles[profileName] = ProfileConfig{
-				IncludeByCadence: mergeCadencePaths(profile.IncludePaths, includeFromFiles),
-				ExcludeByCadence: mergeCadencePaths(profile.ExcludePaths, excludeFromFiles),
-				UseFSSnapshot:    profile.UseFSSnapshot,
-				RepositoryHint:   profile.Repository,
+		// Negated include lines become excludes; negated exclude lines stay in
+		// order because restic treats "!pattern" excludes as re-includes.
+		includeRules := inlineRuleEntries(profile.IncludePaths, RuleKindInclude, merge.sources[profileSourceKey(profileName, "include")])
+		negatedIncludeRules := make([]RuleEntry, 0)
+		for _, rule := range includeFromFiles {
+			if strings.HasPrefix(rule.Path, "!") {
+				rule.Kind = RuleKindExclude
+				rule.Path = strings.TrimPrefix(rule.Path, "!")
+				negatedIncludeRules = append(negatedIncludeRules, rule)
+				continue
 			}
+			includeRules = append(includeRules, rule)
 		}
 
-		return AppConfig{Path: path, Exists: true, Profiles: loadedProfiles}, nil
-	} else if !os.IsNotExist(err) {
-		return AppConfig{}, fmt.Errorf("read config: %w", err)
+		rules := append([]RuleEntry{}, includeRules...)
+		rules = append(rules, presetRules...)
+		rules = append(rules, inlineRuleEntries(profile.ExcludePaths, RuleKindExclude, merge.sources[profileSourceKey(profileName, "exclude")])...)
+		rules = append(rules, excludeFromFiles...)
+		rules = append(rules, negatedIncludeRules...)
+
+		hooks, err := loadHookConfig(profile.Hooks)
+		if err != nil {
+			return AppConfig{}, fmt.Errorf("load hooks for profile %s: %w", profileName, err)
+		}
+		if err := validateSnapshotTags(profile.Tags); err != nil {
+			return AppConfig{}, fmt.Errorf("load tags for profile %s: %w", profileName, err)
+		}
+		if strings.ContainsAny(profile.Host, " \t,") {
+			return AppConfig{}, fmt.Errorf("invalid host for profile %s: %q", profileName, profile.Host)
+		}
+		backupOptions, err := loadBackupOptions(profile.BackupOptions, profileName)
+		if err != nil {
+			return AppConfig{}, fmt.Errorf("load backup_options for profile %s: %w", profileName, err)
+		}
+		if !isValidVSSElevation(profile.VSSElevation) {
+			return AppConfig{}, fmt.Errorf("invalid vss_elevation for profile %s: %q (expected fallback or task)", profileName, profile.VSSElevation)
+		}
+
+		loadedProfiles[profileName] = ProfileConfig{
+			IncludeByCadence: cadencePathsFromRules(rules, RuleKindInclude),
+			ExcludeByCadence: cadencePathsFromRules(rules, RuleKindExclude),
+			Rules:            rules,
+			ExcludePresets:   append([]string{}, profile.ExcludePresets...),
+			UseFSSnapshot:    profile.UseFSSnapshot,
+			RepositoryHint:   profile.Repository,
+			ResticPath:       profile.ResticPath,
+			AllowedOverlaps:  append([]string{}, profile.AllowedOverlaps...),
+			Hooks:            hooks,
+			Tags:             append([]string{}, profile.Tags...),
+			Host:             strings.TrimSpace(profile.Host),
+			HostShortName:    profile.HostShortName,
+			HostLowercase:    profile.HostLowercase,
+			BackupOptions:    backupOptions,
+			VSSElevation:     profile.VSSElevation,
+		}
 	}
 
-	return config, nil
-}
+	if parsed.OverlapPolicy != "" && !isValidOverlapPolicy(parsed.OverlapPolicy) {
+		return AppConfig{}, fmt.Errorf("invalid overlap_policy in config: %q", parsed.OverlapPolicy)
+	}
+	if parsed.OverlapWinner != "" && !isValidOverlapWinner(parsed.OverlapWinner) {
+		return AppConfig{}, fmt.Errorf("invalid overlap_winner in config: %q", parsed.OverlapWinner)
+	}
 
-func mergeCadencePaths(inline CadencePaths, fromFiles CadencePaths) CadencePaths {
-	return CadencePaths{
-		Daily:   append(append([]string{}, inline.Daily...), fromFiles.Daily...),
-		Weekly:  append(append([]string{}, inline.Weekly...), fromFiles.Weekly...),
-		Monthly: append(append([]string{}, inline.Monthly...), fromFiles.Monthly...),
+	notifications, err := loadNotificationConfig(parsed.Notifications, configDir)
+	if err != nil {
+		return AppConfig{}, fmt.Errorf("load notifications: %w", err)
+	}
+
+	for _, version := range []string{parsed.ResticVersion, parsed.ResticMinVersion} {
+		if version != "" && !isVersionString(strings.TrimPrefix(version, "v")) {
+			return AppConfig{}, fmt.Errorf("invalid restic version in config: %q", version)
+		}
 	}
+
+	return AppConfig{
+		Path:             path,
+		Exists:           true,
+		Profiles:         loadedProfiles,
+		Layers:           merge.appliedLayers,
+		Sources:          merge.sources,
+		ResticVersion:    strings.TrimPrefix(parsed.ResticVersion, "v"),
+		ResticMinVersion: strings.TrimPrefix(parsed.ResticMinVersion, "v"),
+		OverlapPolicy:    parsed.OverlapPolicy,
+		OverlapWinner:    parsed.OverlapWinner,
+		Notifications:    notifications,
+		Metrics:          MetricsConfig{Textfile: parsed.Metrics.Textfile},
+	}, nil
 }
 
-func loadCadencePathsFromFiles(files CadencePathFiles, configDir string) (CadencePaths, error) {
-	daily, err := loadPathListFile(files.Daily, configDir)
-	if err != nil {
-		return CadencePaths{}, err
+func inlineRuleEntries(paths CadencePaths, kind string, source string) []RuleEntry {
+	entries := make([]RuleEntry, 0)
+	for _, cadence := range cadences {
+		for _, value := range paths.ForCadence(cadence) {
+			entries = append(entries, RuleEntry{Kind: kind, Cadence: cadence, Path: value, Origin: RuleOriginInline, Source: source})
+		}
 	}
+	return entries
+}
 
-	weekly, err := loadPathListFile(files.Weekly, configDir)
-	if err != nil {
-		return CadencePaths{}, err
+func cadencePathsFromRules(rules []RuleEntry, kind string) CadencePaths {
+	paths := CadencePaths{Daily: []string{}, Weekly: []string{}, Monthly: []string{}}
+	for _, rule := range rules {
+		if rule.Kind != kind {
+			continue
+		}
+		switch rule.Cadence {
+		case "daily":
+			paths.Daily = append(paths.Daily, rule.Path)
+		case "weekly":
+			paths.Weekly = append(paths.Weekly, rule.Path)
+		case "monthly":
+			paths.Monthly = append(paths.Monthly, rule.Path)
+		}
 	}
+	return paths
+}
 
-	monthly, err := loadPathListFile(files.Monthly, configDir)
-	if err != nil {
-		return CadencePaths{}, err
+func loadCadenceRuleFiles(files CadencePathFiles, configDir string, kind string) ([]RuleEntry, error) {
+	entries := make([]RuleEntry, 0)
+	for _, cadence := range cadences {
+		lines, err := loadPathListFile(files.ForCadence(cadence), configDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			entries = append(entries, RuleEntry{Kind: kind, Cadence: cadence, Path: line.Path, Origin: RuleOriginFile, Source: line.Source})
+		}
 	}
+	return entries, nil
+}
 
-	return CadencePaths{Daily: daily, Weekly: weekly, Monthly: monthly}, nil
+type ruleFileLine struct {
+	Path   string
+	Source string
 }
 
-func loadPathListFile(path string, configDir string) ([]string, error) {
+func loadPathListFile(path string, configDir string) ([]ruleFileLine, error) {
 	trimmedPath := strings.TrimSpace(path)
 	if trimmedPath == "" {
-		return []string{}, nil
+		return []ruleFileLine{}, nil
 	}
 
 	resolvedPath := trimmedPath
@@ -243,23 +439,63 @@ func loadPathListFile(path string, configDir string) ([]string, error) {
 		resolvedPath = filepath.Join(configDir, resolvedPath)
 	}
 
-	file, err := os.Open(resolvedPath)
-	if err != nil {
+	if _, err := os.Stat(resolvedPath); err != nil {
 		if os.IsNotExist(err) {
-			return []string{}, nil
+			return []ruleFileLine{}, nil
+		}
+		return nil, fmt.Errorf("open path list file %s: %w", resolvedPath, err)
+	}
+
+	return readPathListFile(filepath.Clean(resolvedPath), nil)
+}
+
+func readPathListFile(resolvedPath string, includeChain []string) ([]ruleFileLine, error) {
+	for _, visited := range includeChain {
+		if visited == resolvedPath {
+			return nil, fmt.Errorf("rule file include cycle: %s", strings.Join(append(append([]string{}, includeChain...), resolvedPath), " -> "))
 		}
+	}
+	chain := append(append([]string{}, includeChain...), resolvedPath)
+
+	file, err := os.Open(resolvedPath)
+	if err != nil {
 		return nil, fmt.Errorf("open path list file %s: %w", resolvedPath, err)
 	}
 	defer file.Close()
 
-	paths := []string{}
+	paths := []ruleFileLine{}
+	lineNumber := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
+		lineNumber++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		paths = append(paths, line)
+
+		if includeTarget, ok := parseIncludeDirective(line); ok {
+			if includeTarget == "" {
+				return nil, fmt.Errorf("%s:%d: @include requires a file path", resolvedPath, lineNumber)
+			}
+			if !filepath.IsAbs(includeTarget) {
+				includeTarget = filepath.Join(filepath.Dir(resolvedPath), includeTarget)
+			}
+			included, includeErr := readPathListFile(filepath.Clean(includeTarget), chain)
+			if includeErr != nil {
+				return nil, includeErr
+			}
+			paths = append(paths, included...)
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			negated := strings.TrimSpace(strings.TrimPrefix(line, "!"))
+			if negated == "" {
+				return nil, fmt.Errorf("%s:%d: negation requires a path", resolvedPath, lineNumber)
+			}
+			line = "!" + negated
+		}
+		paths = append(paths, ruleFileLine{Path: line, Source: resolvedPath})
 	}
 
 	if scanErr := scanner.Err(); scanErr != nil {
@@ -269,6 +505,13 @@ func loadPathListFile(path string, configDir string) ([]string, error) {
 	return paths, nil
 }
 
+func parseIncludeDirective(line string) (string, bool) {
+	if line != "@include" && !strings.HasPrefix(line, "@include ") && !strings.HasPrefix(line, "@include\t") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "@include")), true
+}
+
 func ValidatePlanConfig(plan RunPlan, config AppConfig) error {
 	for _, target := range plan.Targets {
 		if _, ok := config.Profiles[target]; !ok {
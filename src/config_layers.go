@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultSystemConfigPath = "/etc/backup/config.yaml"
+
+func ResolveSystemConfigPath() string {
+	if override := os.Getenv("BACKUP_SYSTEM_CONFIG"); override != "" {
+		return override
+	}
+	return defaultSystemConfigPath
+}
+
+// ResolveConfigLayers returns candidate config files from lowest to highest
+// precedence: the system file, the user file, then config.d/*.yaml fragments
+// next to the user file in lexical order.
+func ResolveConfigLayers(runtime Runtime) ([]string, error) {
+	userPath, err := ResolveConfigPath(runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []string{ResolveSystemConfigPath(), userPath}
+	fragments, err := filepath.Glob(filepath.Join(filepath.Dir(userPath), "config.d", "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("list config fragments: %w", err)
+	}
+	sort.Strings(fragments)
+	return append(layers, fragments...), nil
+}
+
+type configLayerMerge struct {
+	topLevelKeys  []string
+	topLevel      map[string]*yaml.Node
+	profileNames  []string
+	profileKeys   map[string][]string
+	profileValues map[string]map[string]*yaml.Node
+	sources       map[string]string
+	appliedLayers []string
+}
+
+func newConfigLayerMerge() *configLayerMerge {
+	return &configLayerMerge{
+		topLevel:      map[string]*yaml.Node{},
+		profileKeys:   map[string][]string{},
+		profileValues: map[string]map[string]*yaml.Node{},
+		sources:       map[string]string{},
+	}
+}
+
+func (merge *configLayerMerge) apply(layerPath string, data []byte) error {
+	var validated fileAppConfig
+	if err := yaml.Unmarshal(data, &validated); err != nil {
+		return fmt.Errorf("parse config %s: %w", layerPath, err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("parse config %s: %w", layerPath, err)
+	}
+	merge.appliedLayers = append(merge.appliedLayers, layerPath)
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("parse config %s: top level must be a mapping", layerPath)
+	}
+
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		key := root.Content[index].Value
+		value := root.Content[index+1]
+		if key != "profiles" {
+			if _, exists := merge.topLevel[key]; !exists {
+				merge.topLevelKeys = append(merge.topLevelKeys, key)
+			}
+			merge.topLevel[key] = value
+			merge.sources[key] = layerPath
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			continue
+		}
+		for profileIndex := 0; profileIndex+1 < len(value.Content); profileIndex += 2 {
+			profileName := value.Content[profileIndex].Value
+			profileNode := value.Content[profileIndex+1]
+			if _, exists := merge.profileValues[profileName]; !exists {
+				merge.profileNames = append(merge.profileNames, profileName)
+				merge.profileValues[profileName] = map[string]*yaml.Node{}
+			}
+			if profileNode.Kind != yaml.MappingNode {
+				continue
+			}
+			for fieldIndex := 0; fieldIndex+1 < len(profileNode.Content); fieldIndex += 2 {
+				field := profileNode.Content[fieldIndex].Value
+				if _, exists := merge.profileValues[profileName][field]; !exists {
+					merge.profileKeys[profileName] = append(merge.profileKeys[profileName], field)
+				}
+				merge.profileValues[profileName][field] = profileNode.Content[fieldIndex+1]
+				merge.sources[profileSourceKey(profileName, field)] = layerPath
+			}
+		}
+	}
+	return nil
+}
+
+func (merge *configLayerMerge) decode(target *fileAppConfig) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range merge.topLevelKeys {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, merge.topLevel[key])
+	}
+
+	profiles := &yaml.Node{Kind: yaml.MappingNode}
+	for _, profileName := range merge.profileNames {
+		profileNode := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range merge.profileKeys[profileName] {
+			profileNode.Content = append(profileNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, merge.profileValues[profileName][field])
+		}
+		profiles.Content = append(profiles.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: profileName}, profileNode)
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "profiles"}, profiles)
+
+	if err := root.Decode(target); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	return nil
+}
+
+func profileSourceKey(profileName string, field string) string {
+	return "profiles." + profileName + "." + field
+}
@@ -0,0 +1,394 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type DiffRootSummary struct {
+	Root          string
+	Added         int
+	Removed       int
+	Modified      int
+	AddedBytes    uint64
+	RemovedBytes  uint64
+	ModifiedBytes uint64
+}
+
+type SnapshotDiff struct {
+	Profile string
+	From    Snapshot
+	To      Snapshot
+	Live    bool
+	Roots   []DiffRootSummary
+}
+
+type SnapshotFile struct {
+	Size    uint64
+	ModTime time.Time
+}
+
+type resticLsNode struct {
+	StructType string    `json:"struct_type"`
+	Type       string    `json:"type"`
+	Path       string    `json:"path"`
+	Size       uint64    `json:"size"`
+	MTime      time.Time `json:"mtime"`
+}
+
+type resticDiffMessage struct {
+	MessageType string `json:"message_type"`
+	Path        string `json:"path"`
+	Modifier    string `json:"modifier"`
+}
+
+// RunDiff compares two snapshots of a profile, or one snapshot with the live
+// filesystem. Without snapshot arguments it compares the last monthly and
+// last weekly snapshots; a single snapshot is compared with the newest one.
+func RunDiff(command Command, config AppConfig, executor Executor) (string, error) {
+	executable, repository, err := browseRepository(command.Profile, config)
+	if err != nil {
+		return "", err
+	}
+	snapshots, err := ListSnapshots(executable, repository, nil, executor)
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshots in %s repository %s", command.Profile, repository)
+	}
+
+	var from, to Snapshot
+	switch {
+	case command.Live:
+		ref := command.Snapshot
+		if ref == "" {
+			ref = "latest"
+		}
+		if from, err = resolveSnapshotRef(snapshots, ref); err != nil {
+			return "", err
+		}
+	case command.Snapshot == "":
+		monthly, foundMonthly, _ := SelectSnapshot(snapshots, "monthly", "")
+		weekly, foundWeekly, _ := SelectSnapshot(snapshots, "weekly", "")
+		if !foundMonthly || !foundWeekly {
+			return "", fmt.Errorf("diff needs a monthly and a weekly %s snapshot; pass snapshot IDs instead", command.Profile)
+		}
+		from, to = monthly, weekly
+	default:
+		if from, err = resolveSnapshotRef(snapshots, command.Snapshot); err != nil {
+			return "", err
+		}
+		ref := command.CompareTo
+		if ref == "" {
+			ref = "latest"
+		}
+		if to, err = resolveSnapshotRef(snapshots, ref); err != nil {
+			return "", err
+		}
+	}
+	if !command.Live && to.Time.Before(from.Time) {
+		from, to = to, from
+	}
+
+	var diff SnapshotDiff
+	if command.Live {
+		diff, err = DiffSnapshotLive(command.Profile, executable, repository, from, config, executor)
+	} else {
+		diff, err = DiffSnapshots(command.Profile, executable, repository, from, to, executor)
+	}
+	if err != nil {
+		return "", err
+	}
+	return FormatSnapshotDiff(diff), nil
+}
+
+func resolveSnapshotRef(snapshots []Snapshot, ref string) (Snapshot, error) {
+	if ref == "latest" {
+		return snapshots[len(snapshots)-1], nil
+	}
+	matches := make([]Snapshot, 0, 1)
+	for _, snapshot := range snapshots {
+		if strings.HasPrefix(snapshot.ID, ref) {
+			matches = append(matches, snapshot)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Snapshot{}, fmt.Errorf("no snapshot matches %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return Snapshot{}, fmt.Errorf("snapshot prefix %q is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// DiffSnapshots classifies the changes restic diff reports by include root.
+// restic diff only names paths, so sizes come from listing both snapshots.
+func DiffSnapshots(profileName string, executable string, repository string, from Snapshot, to Snapshot, executor Executor) (SnapshotDiff, error) {
+	output, err := executor.Run(executable, "-r", repository, "diff", "--json", from.ID, to.ID)
+	if err != nil {
+		return SnapshotDiff{}, fmt.Errorf("diff %s %s: %w", from.ShortID, to.ShortID, err)
+	}
+	fromFiles, err := listSnapshotFiles(executable, repository, from, executor)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	toFiles, err := listSnapshotFiles(executable, repository, to, executor)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	summaries := newDiffSummaries(append(append([]string{}, from.Paths...), to.Paths...))
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var message resticDiffMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || message.MessageType != "change" {
+			continue
+		}
+		// Directories end in a slash; their content is reported separately.
+		if strings.HasSuffix(message.Path, "/") {
+			continue
+		}
+		summary := summaries.forPath(message.Path)
+		switch message.Modifier {
+		case "+":
+			summary.Added++
+			summary.AddedBytes += toFiles[message.Path].Size
+		case "-":
+			summary.Removed++
+			summary.RemovedBytes += fromFiles[message.Path].Size
+		case "M", "T":
+			summary.Modified++
+			summary.ModifiedBytes += toFiles[message.Path].Size
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return SnapshotDiff{}, fmt.Errorf("read diff output: %w", err)
+	}
+	return SnapshotDiff{Profile: profileName, From: from, To: to, Roots: summaries.sorted()}, nil
+}
+
+// DiffSnapshotLive walks the snapshot's roots on disk and compares size and
+// mtime with the snapshot listing. The profile's excludes for the snapshot's
+// cadence are matched the way restic matches them, so files restic would
+// skip are not reported as added.
+func DiffSnapshotLive(profileName string, executable string, repository string, from Snapshot, config AppConfig, executor Executor) (SnapshotDiff, error) {
+	fromFiles, err := listSnapshotFiles(executable, repository, from, executor)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	translator := pathTranslatorLoader()
+	excludes := compileExcludeRules(config.Profiles[profileName].ExcludeByCadence.ForCadence(from.Cadence()), translator)
+	summaries := newDiffSummaries(from.Paths)
+	seen := map[string]bool{}
+
+	for _, root := range summaries.roots {
+		localRoot := localSnapshotPath(profileName, root)
+		walkErr := filepath.WalkDir(localRoot, func(localPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if localPath == localRoot {
+					return fs.SkipAll
+				}
+				return nil
+			}
+			if isLiveExcluded(localPath, entry.IsDir(), excludes, translator) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			relative, err := filepath.Rel(localRoot, localPath)
+			if err != nil {
+				return nil
+			}
+			treePath := path.Join(root, filepath.ToSlash(relative))
+			seen[treePath] = true
+			summary := summaries.forPath(treePath)
+			stored, ok := fromFiles[treePath]
+			switch {
+			case !ok:
+				summary.Added++
+				summary.AddedBytes += uint64(info.Size())
+			case stored.Size != uint64(info.Size()) || !stored.ModTime.Truncate(time.Second).Equal(info.ModTime().Truncate(time.Second)):
+				summary.Modified++
+				summary.ModifiedBytes += uint64(info.Size())
+			}
+			return nil
+		})
+		if walkErr != nil {
+			return SnapshotDiff{}, fmt.Errorf("walk %s: %w", localRoot, walkErr)
+		}
+	}
+	for treePath, stored := range fromFiles {
+		if seen[treePath] {
+			continue
+		}
+		summary := summaries.forPath(treePath)
+		summary.Removed++
+		summary.RemovedBytes += stored.Size
+	}
+	return SnapshotDiff{Profile: profileName, From: from, Live: true, Roots: summaries.sorted()}, nil
+}
+
+func listSnapshotFiles(executable string, repository string, snapshot Snapshot, executor Executor) (map[string]SnapshotFile, error) {
+	output, err := executor.Run(executable, "-r", repository, "ls", "--json", snapshot.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list files in snapshot %s: %w", snapshot.ShortID, err)
+	}
+	files := map[string]SnapshotFile{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var node resticLsNode
+		if err := json.Unmarshal(scanner.Bytes(), &node); err != nil || node.StructType != "node" || node.Type != "file" {
+			continue
+		}
+		files[node.Path] = SnapshotFile{Size: node.Size, ModTime: node.MTime}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read file list of snapshot %s: %w", snapshot.ShortID, err)
+	}
+	return files, nil
+}
+
+// resticTreePath is the form restic uses inside a snapshot: a Windows
+// source `C:\Users\me` is stored as `/C/Users/me`.
+func resticTreePath(sourcePath string) string {
+	slashed := strings.ReplaceAll(sourcePath, `\`, "/")
+	if isDrivePath(slashed) {
+		return path.Clean("/" + slashed[:1] + slashed[2:])
+	}
+	return path.Clean(slashed)
+}
+
+func isResticDriveTreePath(treePath string) bool {
+	return len(treePath) >= 2 && treePath[0] == '/' && isASCIILetter(treePath[1]) && (len(treePath) == 2 || treePath[2] == '/')
+}
+
+func localSnapshotPath(profileName string, treePath string) string {
+	if profileName != "windows" || !isResticDriveTreePath(treePath) {
+		return treePath
+	}
+	windowsPath := treePath[1:2] + ":" + strings.ReplaceAll(treePath[2:], "/", `\`)
+	if !strings.Contains(windowsPath, `\`) {
+		windowsPath += `\`
+	}
+	if translated, ok := pathTranslatorLoader().ToWSL(windowsPath); ok {
+		return translated.Path
+	}
+	return treePath
+}
+
+func isLiveExcluded(localPath string, isDir bool, rules excludeRules, translator PathTranslator) bool {
+	target := TranslatedPath{Path: localPath, Key: localPath}
+	if translated, ok := translator.ToWSL(localPath); ok {
+		target = translated
+	}
+	index := excludingRuleIndex(rules, target)
+	if index < 0 {
+		return false
+	}
+	// An excluded directory is still walked when a later "!" rule may bring
+	// back something inside it.
+	return !isDir || !rules.reincludeBelow(index, target)
+}
+
+type diffSummaries struct {
+	roots   []string
+	byRoot  map[string]*DiffRootSummary
+	unknown *DiffRootSummary
+}
+
+func newDiffSummaries(sourcePaths []string) *diffSummaries {
+	summaries := &diffSummaries{byRoot: map[string]*DiffRootSummary{}}
+	for _, sourcePath := range sourcePaths {
+		root := resticTreePath(sourcePath)
+		if _, exists := summaries.byRoot[root]; exists {
+			continue
+		}
+		summaries.roots = append(summaries.roots, root)
+		summaries.byRoot[root] = &DiffRootSummary{Root: root}
+	}
+	sort.Strings(summaries.roots)
+	return summaries
+}
+
+// forPath returns the summary of the longest root containing treePath.
+func (summaries *diffSummaries) forPath(treePath string) *DiffRootSummary {
+	best := ""
+	for _, root := range summaries.roots {
+		if (treePath == root || strings.HasPrefix(treePath, strings.TrimSuffix(root, "/")+"/")) && len(root) > len(best) {
+			best = root
+		}
+	}
+	if best != "" {
+		return summaries.byRoot[best]
+	}
+	if summaries.unknown == nil {
+		summaries.unknown = &DiffRootSummary{Root: "(outside include roots)"}
+	}
+	return summaries.unknown
+}
+
+func (summaries *diffSummaries) sorted() []DiffRootSummary {
+	result := make([]DiffRootSummary, 0, len(summaries.roots)+1)
+	for _, root := range summaries.roots {
+		result = append(result, *summaries.byRoot[root])
+	}
+	if summaries.unknown != nil {
+		result = append(result, *summaries.unknown)
+	}
+	return result
+}
+
+func describeDiffSnapshot(snapshot Snapshot) string {
+	cadence := snapshot.Cadence()
+	if cadence == "" {
+		cadence = "untagged"
+	}
+	return fmt.Sprintf("%s (%s, %s)", snapshot.ShortID, snapshot.Time.Local().Format("2006-01-02 15:04"), cadence)
+}
+
+func FormatSnapshotDiff(diff SnapshotDiff) string {
+	to := "live filesystem"
+	if !diff.Live {
+		to = describeDiffSnapshot(diff.To)
+	}
+	lines := []string{fmt.Sprintf("%s: %s -> %s", diff.Profile, describeDiffSnapshot(diff.From), to)}
+	total := DiffRootSummary{Root: "total"}
+	for _, summary := range diff.Roots {
+		lines = append(lines, "  "+formatDiffSummary(summary))
+		total.Added += summary.Added
+		total.Removed += summary.Removed
+		total.Modified += summary.Modified
+		total.AddedBytes += summary.AddedBytes
+		total.RemovedBytes += summary.RemovedBytes
+		total.ModifiedBytes += summary.ModifiedBytes
+	}
+	lines = append(lines, "  "+formatDiffSummary(total))
+	return joinLines(lines)
+}
+
+func formatDiffSummary(summary DiffRootSummary) string {
+	return fmt.Sprintf("%s: %d added (%s), %d removed (%s), %d modified (%s)",
+		summary.Root,
+		summary.Added, formatBytes(summary.AddedBytes),
+		summary.Removed, formatBytes(summary.RemovedBytes),
+		summary.Modified, formatBytes(summary.ModifiedBytes))
+}
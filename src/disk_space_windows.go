@@ -0,0 +1,23 @@
+//go:build windows
+
+package backup
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func diskFreeBytes(path string) (uint64, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable uint64
+	procedure := syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+	result, _, callErr := procedure.Call(uintptr(unsafe.Pointer(pathPointer)), uintptr(unsafe.Pointer(&freeBytesAvailable)), 0, 0)
+	if result == 0 {
+		return 0, callErr
+	}
+	return freeBytesAvailable, nil
+}
@@ -0,0 +1,327 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type DoctorStatus string
+
+const (
+	DoctorPass DoctorStatus = "pass"
+	DoctorWarn DoctorStatus = "warn"
+	DoctorFail DoctorStatus = "fail"
+)
+
+type DoctorCheck struct {
+	Profile string
+	Name    string
+	Status  DoctorStatus
+	Detail  string
+	Hint    string
+}
+
+const (
+	doctorFreeSpaceWarnBytes = 10 << 30
+	doctorFreeSpaceFailBytes = 1 << 30
+)
+
+var lookPath = exec.LookPath
+var diskFreeProbe = diskFreeBytes
+
+func SetLookPathForTests(finder func(string) (string, error)) {
+	if finder == nil {
+		lookPath = exec.LookPath
+		return
+	}
+	lookPath = finder
+}
+
+func SetDiskFreeProbeForTests(probe func(string) (uint64, error)) {
+	if probe == nil {
+		diskFreeProbe = diskFreeBytes
+		return
+	}
+	diskFreeProbe = probe
+}
+
+func RunDoctorChecks(targets []string, config AppConfig, executor Executor) []DoctorCheck {
+	checks := make([]DoctorCheck, 0)
+	for _, target := range targets {
+		profile, ok := config.Profiles[target]
+		if !ok {
+			checks = append(checks, DoctorCheck{Profile: target, Name: "profile", Status: DoctorFail, Detail: "missing profile config", Hint: fmt.Sprintf("add profiles.%s to %s", target, config.Path)})
+			continue
+		}
+
+		if target == "windows" {
+			checks = append(checks, doctorInteropChecks(target)...)
+		}
+
+		executable := resticExecutable(target, profile)
+		versionCheck := doctorResticCheck(target, executable, config, executor)
+		checks = append(checks, versionCheck)
+
+		checks = append(checks, doctorPasswordCheck(target))
+		if versionCheck.Status != DoctorFail {
+			checks = append(checks, doctorRepositoryChecks(target, executable, profile, executor)...)
+		}
+		checks = append(checks, doctorIncludePathChecks(target, profile)...)
+		checks = append(checks, doctorFreeSpaceCheck(target, profile))
+	}
+	return checks
+}
+
+func doctorInteropChecks(target string) []DoctorCheck {
+	checks := make([]DoctorCheck, 0, 2)
+	for _, binary := range []string{"powershell.exe", "wslpath"} {
+		if resolved, err := lookPath(binary); err != nil {
+			checks = append(checks, DoctorCheck{Profile: target, Name: "interop " + binary, Status: DoctorFail, Detail: "not found on PATH", Hint: "enable WSL interop ([interop] enabled=true and appendWindowsPath=true in /etc/wsl.conf), then run 'wsl --shutdown'"})
+		} else {
+			checks = append(checks, DoctorCheck{Profile: target, Name: "interop " + binary, Status: DoctorPass, Detail: resolved})
+		}
+	}
+	return checks
+}
+
+func doctorResticCheck(target string, executable string, config AppConfig, executor Executor) DoctorCheck {
+	check := DoctorCheck{Profile: target, Name: "restic binary"}
+	if _, err := lookPath(executable); err != nil {
+		check.Status = DoctorFail
+		check.Detail = fmt.Sprintf("%s not found", executable)
+		check.Hint = "install restic with scripts/install_restic_wsl_fedora.sh or set restic_path for this profile"
+		return check
+	}
+
+	versionCheck := CheckResticVersion(target, executable, config, executor)
+	if versionCheck.Err != nil {
+		check.Status = DoctorFail
+		check.Detail = versionCheck.Err.Error()
+		check.Hint = fmt.Sprintf("install restic %s (scripts/update_restic_version.sh keeps both sides in sync)", ResticVersionRequirement(config))
+		return check
+	}
+
+	check.Status = DoctorPass
+	check.Detail = fmt.Sprintf("%s %s", executable, versionCheck.Found)
+	return check
+}
+
+func doctorPasswordCheck(target string) DoctorCheck {
+	check := DoctorCheck{Profile: target, Name: "password source"}
+
+	source := ""
+	switch {
+	case os.Getenv("RESTIC_PASSWORD_COMMAND") != "":
+		source = "RESTIC_PASSWORD_COMMAND"
+	case os.Getenv("RESTIC_PASSWORD_FILE") != "":
+		source = "RESTIC_PASSWORD_FILE"
+		passwordFile := os.Getenv("RESTIC_PASSWORD_FILE")
+		if _, err := os.Stat(passwordFile); err != nil {
+			check.Status = DoctorFail
+			check.Detail = fmt.Sprintf("RESTIC_PASSWORD_FILE is not readable: %v", err)
+			check.Hint = "point RESTIC_PASSWORD_FILE at an existing file"
+			return check
+		}
+	case os.Getenv("RESTIC_PASSWORD") != "":
+		source = "RESTIC_PASSWORD"
+	}
+
+	if source == "" {
+		check.Status = DoctorFail
+		check.Detail = "no RESTIC_PASSWORD, RESTIC_PASSWORD_FILE or RESTIC_PASSWORD_COMMAND set"
+		check.Hint = "export one of the restic password variables before running backup"
+		return check
+	}
+
+	check.Status = DoctorPass
+	check.Detail = source
+	return check
+}
+
+func doctorRepositoryChecks(target string, executable string, profile ProfileConfig, executor Executor) []DoctorCheck {
+	repositoryCheck := DoctorCheck{Profile: target, Name: "repository"}
+	if strings.TrimSpace(profile.RepositoryHint) == "" {
+		repositoryCheck.Status = DoctorFail
+		repositoryCheck.Detail = "no repository configured"
+		repositoryCheck.Hint = fmt.Sprintf("set profiles.%s.repository", target)
+		return []DoctorCheck{repositoryCheck}
+	}
+
+	executor = targetExecutor(target, executor)
+	if _, err := executor.Run(executable, "-r", profile.RepositoryHint, "cat", "config"); err != nil {
+		message := strings.ToLower(err.Error())
+		repositoryCheck.Status = DoctorFail
+		repositoryCheck.Detail = err.Error()
+		switch {
+		case strings.Contains(message, "wrong password") || strings.Contains(message, "no key found"):
+			repositoryCheck.Name = "repository password"
+			repositoryCheck.Hint = "the password source does not open this repository; check RESTIC_PASSWORD*"
+		case strings.Contains(message, "locked"):
+			repositoryCheck.Hint = fmt.Sprintf("another restic process holds the lock; if none is running, run '%s -r %s unlock'", executable, profile.RepositoryHint)
+		default:
+			repositoryCheck.Hint = fmt.Sprintf("check the repository path/credentials, or initialize it with '%s -r %s init'", executable, profile.RepositoryHint)
+		}
+		return []DoctorCheck{repositoryCheck}
+	}
+	repositoryCheck.Status = DoctorPass
+	repositoryCheck.Detail = fmt.Sprintf("%s reachable", profile.RepositoryHint)
+
+	lockCheck := DoctorCheck{Profile: target, Name: "repository locks"}
+	output, err := executor.Run(executable, "-r", profile.RepositoryHint, "list", "locks", "--no-lock")
+	if err != nil {
+		lockCheck.Status = DoctorWarn
+		lockCheck.Detail = fmt.Sprintf("could not list locks: %v", err)
+		return []DoctorCheck{repositoryCheck, lockCheck}
+	}
+	lockCount := 0
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lockCount++
+		}
+	}
+	if lockCount > 0 {
+		lockCheck.Status = DoctorWarn
+		lockCheck.Detail = fmt.Sprintf("%d lock(s) present", lockCount)
+		lockCheck.Hint = fmt.Sprintf("if no backup is running, remove stale locks with '%s -r %s unlock'", executable, profile.RepositoryHint)
+	} else {
+		lockCheck.Status = DoctorPass
+		lockCheck.Detail = "no locks"
+	}
+	return []DoctorCheck{repositoryCheck, lockCheck}
+}
+
+func doctorIncludePathChecks(target string, profile ProfileConfig) []DoctorCheck {
+	checks := make([]DoctorCheck, 0)
+	seen := map[string]struct{}{}
+	for _, cadence := range []string{"daily", "weekly", "monthly"} {
+		for _, includePath := range profile.IncludeByCadence.ForCadence(cadence) {
+			if _, exists := seen[includePath]; exists {
+				continue
+			}
+			seen[includePath] = struct{}{}
+
+			check := DoctorCheck{Profile: target, Name: "include path", Detail: includePath}
+			localPath, ok := localPathForProfile(includePath)
+			if !ok {
+				check.Status = DoctorWarn
+				check.Detail = fmt.Sprintf("%s (cannot be checked from WSL)", includePath)
+				checks = append(checks, check)
+				continue
+			}
+			if _, err := os.Stat(localPath); err != nil {
+				check.Status = DoctorFail
+				check.Detail = fmt.Sprintf("%s: %v", includePath, err)
+				check.Hint = "create the path or remove it from the include rules"
+			} else {
+				check.Status = DoctorPass
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+var repositoryBackendPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]+:`)
+
+func doctorFreeSpaceCheck(target string, profile ProfileConfig) DoctorCheck {
+	check := DoctorCheck{Profile: target, Name: "repository free space"}
+	repository := strings.TrimSpace(profile.RepositoryHint)
+	if repository == "" || repositoryBackendPattern.MatchString(repository) {
+		check.Status = DoctorPass
+		check.Detail = "remote or unset repository; skipped"
+		return check
+	}
+
+	localPath, ok := localPathForProfile(repository)
+	if !ok {
+		check.Status = DoctorWarn
+		check.Detail = fmt.Sprintf("%s cannot be checked from WSL", repository)
+		return check
+	}
+	for {
+		if _, err := os.Stat(localPath); err == nil {
+			break
+		}
+		parent := filepath.Dir(localPath)
+		if parent == localPath {
+			break
+		}
+		localPath = parent
+	}
+
+	free, err := diskFreeProbe(localPath)
+	if err != nil {
+		check.Status = DoctorWarn
+		check.Detail = fmt.Sprintf("could not read free space for %s: %v", localPath, err)
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("%s free at %s", formatBytes(free), localPath)
+	switch {
+	case free < doctorFreeSpaceFailBytes:
+		check.Status = DoctorFail
+		check.Hint = "free up space on the repository disk or prune old snapshots"
+	case free < doctorFreeSpaceWarnBytes:
+		check.Status = DoctorWarn
+		check.Hint = "repository disk is getting full; consider pruning old snapshots"
+	default:
+		check.Status = DoctorPass
+	}
+	return check
+}
+
+func localPathForProfile(rawPath string) (string, bool) {
+	expanded := os.ExpandEnv(strings.TrimSpace(rawPath))
+	translated, ok := pathTranslatorLoader().ToWSL(expanded)
+	if !ok {
+		return "", false
+	}
+	return translated.Path, true
+}
+
+func formatBytes(value uint64) string {
+	const unit = 1024
+	if value < unit {
+		return fmt.Sprintf("%d B", value)
+	}
+	divisor, exponent := uint64(unit), 0
+	for quotient := value / unit; quotient >= unit; quotient /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(value)/float64(divisor), "KMGTPE"[exponent])
+}
+
+func FormatDoctorReport(checks []DoctorCheck) string {
+	lines := make([]string, 0, len(checks))
+	currentProfile := ""
+	for _, check := range checks {
+		if check.Profile != currentProfile {
+			currentProfile = check.Profile
+			lines = append(lines, fmt.Sprintf("%s:", currentProfile))
+		}
+		line := fmt.Sprintf("  [%s] %s", check.Status, check.Name)
+		if check.Detail != "" {
+			line += ": " + check.Detail
+		}
+		lines = append(lines, line)
+		if check.Hint != "" && check.Status != DoctorPass {
+			lines = append(lines, "         hint: "+check.Hint)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func countDoctorStatus(checks []DoctorCheck, status DoctorStatus) int {
+	count := 0
+	for _, check := range checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
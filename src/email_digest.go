@@ -0,0 +1,388 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+	SMTPSecurityNone     = "none"
+)
+
+const defaultDigestInterval = 7 * 24 * time.Hour
+
+const defaultDigestSubject = "backup digest for {{.Host}}: {{.Failures}} failed, {{.Successes}} succeeded"
+
+const defaultDigestTemplate = `Backup runs on {{.Host}} from {{.Since.Format "2006-01-02 15:04"}} to {{.Until.Format "2006-01-02 15:04"}}.
+
+{{if .Records}}{{range .Records}}{{.FinishedAt.Format "2006-01-02 15:04"}}  {{printf "%-7s" .Cadence}} {{printf "%-8s" .Profile}} {{if .Success}}ok{{if .SupersededBy}} (covered by {{.SupersededBy}}){{end}}{{else}}FAILED: {{.Error}}{{end}}
+{{end}}{{else}}No runs were recorded in this period.
+{{end}}
+{{.Successes}} succeeded, {{.Failures}} failed.
+`
+
+type EmailDigestConfig struct {
+	Host            string
+	Port            int
+	Security        string
+	From            string
+	To              []string
+	Username        string
+	PasswordEnv     string
+	PasswordFile    string
+	PasswordCommand string
+	Interval        time.Duration
+	Subject         string
+	TemplatePath    string
+}
+
+type fileEmailDigestConfig struct {
+	Host            string   `yaml:"host"`
+	Port            int      `yaml:"port"`
+	TLS             string   `yaml:"tls"`
+	From            string   `yaml:"from"`
+	To              []string `yaml:"to"`
+	Username        string   `yaml:"username"`
+	PasswordEnv     string   `yaml:"password_env"`
+	PasswordFile    string   `yaml:"password_file"`
+	PasswordCommand string   `yaml:"password_command"`
+	Interval        string   `yaml:"interval"`
+	Subject         string   `yaml:"subject"`
+	Template        string   `yaml:"template"`
+}
+
+type DigestData struct {
+	Host      string
+	Since     time.Time
+	Until     time.Time
+	Records   []RunRecord
+	Successes int
+	Failures  int
+}
+
+type digestState struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+func (config EmailDigestConfig) Enabled() bool {
+	return config.Host != ""
+}
+
+func loadEmailDigestConfig(file fileEmailDigestConfig, configDir string) (EmailDigestConfig, error) {
+	if file.Host == "" {
+		return EmailDigestConfig{}, nil
+	}
+	if file.From == "" || len(file.To) == 0 {
+		return EmailDigestConfig{}, fmt.Errorf("email digest requires from and to")
+	}
+
+	security := file.TLS
+	if security == "" {
+		security = SMTPSecurityStartTLS
+	}
+	port := file.Port
+	switch security {
+	case SMTPSecurityStartTLS:
+		if port == 0 {
+			port = 587
+		}
+	case SMTPSecurityTLS:
+		if port == 0 {
+			port = 465
+		}
+	case SMTPSecurityNone:
+		if port == 0 {
+			port = 25
+		}
+	default:
+		return EmailDigestConfig{}, fmt.Errorf("invalid email tls mode %q (expected starttls, tls or none)", file.TLS)
+	}
+
+	secretSources := 0
+	for _, source := range []string{file.PasswordEnv, file.PasswordFile, file.PasswordCommand} {
+		if source != "" {
+			secretSources++
+		}
+	}
+	if secretSources > 1 {
+		return EmailDigestConfig{}, fmt.Errorf("email digest accepts only one of password_env, password_file and password_command")
+	}
+
+	interval := defaultDigestInterval
+	if file.Interval != "" {
+		parsed, err := time.ParseDuration(file.Interval)
+		if err != nil || parsed <= 0 {
+			return EmailDigestConfig{}, fmt.Errorf("invalid email digest interval: %q", file.Interval)
+		}
+		interval = parsed
+	}
+
+	subject := file.Subject
+	if subject == "" {
+		subject = defaultDigestSubject
+	}
+	templatePath := file.Template
+	if templatePath != "" && !filepath.IsAbs(templatePath) {
+		templatePath = filepath.Join(configDir, templatePath)
+	}
+	passwordFile := file.PasswordFile
+	if passwordFile != "" && !filepath.IsAbs(passwordFile) {
+		passwordFile = filepath.Join(configDir, passwordFile)
+	}
+
+	return EmailDigestConfig{
+		Host:            file.Host,
+		Port:            port,
+		Security:        security,
+		From:            file.From,
+		To:              append([]string{}, file.To...),
+		Username:        file.Username,
+		PasswordEnv:     file.PasswordEnv,
+		PasswordFile:    passwordFile,
+		PasswordCommand: file.PasswordCommand,
+		Interval:        interval,
+		Subject:         subject,
+		TemplatePath:    templatePath,
+	}, nil
+}
+
+func resolveSMTPPassword(config EmailDigestConfig, executor Executor) (string, error) {
+	switch {
+	case config.PasswordEnv != "":
+		password := os.Getenv(config.PasswordEnv)
+		if password == "" {
+			return "", fmt.Errorf("smtp password env %s is empty", config.PasswordEnv)
+		}
+		return password, nil
+	case config.PasswordFile != "":
+		data, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("read smtp password file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case config.PasswordCommand != "":
+		output, err := executor.Run("sh", "-c", config.PasswordCommand)
+		if err != nil {
+			return "", fmt.Errorf("smtp password command: %w", err)
+		}
+		return strings.TrimSpace(output), nil
+	default:
+		return "", nil
+	}
+}
+
+func BuildDigestData(records []RunRecord, since time.Time, until time.Time) DigestData {
+	host, _ := os.Hostname()
+	data := DigestData{Host: host, Since: since, Until: until, Records: make([]RunRecord, 0)}
+	for _, record := range records {
+		if !record.FinishedAt.After(since) || record.FinishedAt.After(until) {
+			continue
+		}
+		data.Records = append(data.Records, record)
+		if record.Success {
+			data.Successes++
+		} else {
+			data.Failures++
+		}
+	}
+	return data
+}
+
+func RenderDigest(config EmailDigestConfig, data DigestData) (string, string, error) {
+	bodyTemplate := defaultDigestTemplate
+	if config.TemplatePath != "" {
+		content, err := os.ReadFile(config.TemplatePath)
+		if err != nil {
+			return "", "", fmt.Errorf("read digest template: %w", err)
+		}
+		bodyTemplate = string(content)
+	}
+	subjectTemplate := config.Subject
+	if subjectTemplate == "" {
+		subjectTemplate = defaultDigestSubject
+	}
+
+	render := func(name string, text string) (string, error) {
+		parsed, err := template.New(name).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("parse digest %s template: %w", name, err)
+		}
+		var buffer bytes.Buffer
+		if err := parsed.Execute(&buffer, data); err != nil {
+			return "", fmt.Errorf("render digest %s: %w", name, err)
+		}
+		return buffer.String(), nil
+	}
+	subject, err := render("subject", subjectTemplate)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := render("body", bodyTemplate)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject), body, nil
+}
+
+func buildDigestMessage(config EmailDigestConfig, subject string, body string, sentAt time.Time) []byte {
+	headers := []string{
+		"From: " + config.From,
+		"To: " + strings.Join(config.To, ", "),
+		"Subject: " + subject,
+		"Date: " + sentAt.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	normalizedBody := strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + normalizedBody)
+}
+
+func SendDigestEmail(config EmailDigestConfig, subject string, body string, executor Executor) error {
+	password, err := resolveSMTPPassword(config, executor)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host}
+	var client *smtp.Client
+	if config.Security == SMTPSecurityTLS {
+		connection, err := tls.Dial("tcp", address, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("connect to smtp server: %w", err)
+		}
+		client, err = smtp.NewClient(connection, config.Host)
+		if err != nil {
+			return fmt.Errorf("smtp handshake: %w", err)
+		}
+	} else {
+		client, err = smtp.Dial(address)
+		if err != nil {
+			return fmt.Errorf("connect to smtp server: %w", err)
+		}
+	}
+	defer client.Close()
+
+	if config.Security == SMTPSecurityStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", config.Username, password, config.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(config.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, recipient := range config.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := writer.Write(buildDigestMessage(config, subject, body, clock())); err != nil {
+		return fmt.Errorf("smtp write message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp send message: %w", err)
+	}
+	return client.Quit()
+}
+
+func digestStatePath() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "digest.json"), nil
+}
+
+func loadDigestState() (digestState, bool, error) {
+	statePath, err := digestStatePath()
+	if err != nil {
+		return digestState{}, false, err
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return digestState{}, false, nil
+		}
+		return digestState{}, false, fmt.Errorf("read digest state: %w", err)
+	}
+	var state digestState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return digestState{}, false, fmt.Errorf("parse digest state: %w", err)
+	}
+	return state, true, nil
+}
+
+func saveDigestState(state digestState) error {
+	statePath, err := digestStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath, data, 0o644)
+}
+
+// SendDigest mails the runs recorded since the previous digest. Unless forced
+// it only sends once the configured interval has passed; the very first call
+// just starts the period so a new setup does not mail the whole history.
+func SendDigest(config EmailDigestConfig, force bool, executor Executor) (bool, error) {
+	if !config.Enabled() {
+		return false, fmt.Errorf("email digest is not configured")
+	}
+	state, hasState, err := loadDigestState()
+	if err != nil {
+		return false, err
+	}
+	now := clock()
+	if !force {
+		if !hasState {
+			return false, saveDigestState(digestState{LastSent: now})
+		}
+		if now.Sub(state.LastSent) < config.Interval {
+			return false, nil
+		}
+	}
+	since := state.LastSent
+	if !hasState {
+		since = now.Add(-config.Interval)
+	}
+
+	records, err := LoadRunHistory()
+	if err != nil {
+		return false, err
+	}
+	subject, body, err := RenderDigest(config, BuildDigestData(records, since, now))
+	if err != nil {
+		return false, err
+	}
+	if err := SendDigestEmail(config, subject, body, executor); err != nil {
+		return false, err
+	}
+	return true, saveDigestState(digestState{LastSent: now})
+}
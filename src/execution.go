@@ -1,58 +1,119 @@
 package backup
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Executor interface {
 	Run(name string, args ...string) (string, error)
 }
 
+// CommandOptions carries the extras some callers need beyond argv. Executors
+// that do not implement OptionsExecutor run the command without them.
+type CommandOptions struct {
+	Dir     string
+	Env     []string
+	Timeout time.Duration
+}
+
+type OptionsExecutor interface {
+	Executor
+	RunWithOptions(options CommandOptions, name string, args ...string) (string, error)
+}
+
+func runWithOptions(executor Executor, options CommandOptions, name string, args ...string) (string, error) {
+	if optionsExecutor, ok := executor.(OptionsExecutor); ok {
+		return optionsExecutor.RunWithOptions(options, name, args...)
+	}
+	return executor.Run(name, args...)
+}
+
 type SystemExecutor struct{}
 
 func (executor SystemExecutor) Run(name string, args ...string) (string, error) {
-	command := exec.Command(name, args...)
-	output, err := command.CombinedOutput()
+	return executor.RunWithOptions(CommandOptions{}, name, args...)
+}
+
+func (executor SystemExecutor) RunWithOptions(options CommandOptions, name string, args ...string) (string, error) {
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	command := exec.CommandContext(ctx, name, args...)
+	command.Dir = options.Dir
+	if len(options.Env) > 0 {
+		command.Env = append(os.Environ(), options.Env...)
+	}
+	rawOutput, err := command.CombinedOutput()
+	output := strings.TrimSpace(DecodeConsoleOutput(rawOutput))
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command timed out after %s: %s", options.Timeout, output)
+	}
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(string(output)))
+		return "", fmt.Errorf("command failed: %w: %s", err, output)
 	}
-	return strings.TrimSpace(string(output)), nil
+	return output, nil
 }
 
 type ExecutionResult struct {
-	Target string
-	Output string
+	Target   string
+	Output   string
+	Err      error
+	Duration time.Duration
+	Warnings []string
 }
 
-func ExecuteResticInvocations(invocations []ResticInvocation, executor Executor) ([]ExecutionResult, error) {
+// ExecuteResticInvocationsDetailed runs every invocation to completion and
+// reports each outcome, so one failing profile does not hide the others.
+func ExecuteResticInvocationsDetailed(invocations []ResticInvocation, executor Executor) []ExecutionResult {
+	return executeInParallel(invocations, func(invocation ResticInvocation) ExecutionResult {
+		return runResticInvocation(invocation, executor)
+	})
+}
+
+func runResticInvocation(invocation ResticInvocation, executor Executor) ExecutionResult {
+	startedAt := time.Now()
+	output, err := targetExecutor(invocation.Target, executor).Run(invocation.Executable, invocation.Args...)
+	return ExecutionResult{Target: invocation.Target, Output: output, Err: err, Duration: time.Since(startedAt)}
+}
+
+func executeInParallel(invocations []ResticInvocation, run func(ResticInvocation) ExecutionResult) []ExecutionResult {
 	results := make([]ExecutionResult, len(invocations))
-	errors := make([]error, len(invocations))
 
 	var waitGroup sync.WaitGroup
 	for invocationIndex := range invocations {
 		waitGroup.Add(1)
 		go func(index int) {
 			defer waitGroup.Done()
-			invocation := invocations[index]
-			output, err := executor.Run(invocation.Executable, invocation.Args...)
-			if err != nil {
-				errors[index] = err
-				return
-			}
-			results[index] = ExecutionResult{Target: invocation.Target, Output: output}
+			results[index] = run(invocations[index])
 		}(invocationIndex)
 	}
 
 	waitGroup.Wait()
+	return results
+}
 
-	for invocationIndex := range errors {
-		if errors[invocationIndex] != nil {
-			return nil, fmt.Errorf("%s invocation failed: %w", invocations[invocationIndex].Target, errors[invocationIndex])
+func firstExecutionError(results []ExecutionResult) error {
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("%s invocation failed: %w", result.Target, result.Err)
 		}
 	}
+	return nil
+}
 
+func ExecuteResticInvocations(invocations []ResticInvocation, executor Executor) ([]ExecutionResult, error) {
+	results := ExecuteResticInvocationsDetailed(invocations, executor)
+	if err := firstExecutionError(results); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FindHit is one version of a file: a path with a given size and mtime and
+// the range of snapshots that contain it unchanged.
+type FindHit struct {
+	Profile   string
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	FirstSeen Snapshot
+	LastSeen  Snapshot
+	Snapshots int
+}
+
+type findMatch struct {
+	Path  string    `json:"path"`
+	Type  string    `json:"type"`
+	Size  int64     `json:"size"`
+	MTime time.Time `json:"mtime"`
+}
+
+type findSnapshotResult struct {
+	Snapshot string      `json:"snapshot"`
+	Matches  []findMatch `json:"matches"`
+}
+
+type FindFilter struct {
+	Cadence string
+	Since   string
+}
+
+// FindInProfile runs restic find over the profile's snapshots that pass the
+// filter and folds matches of the same path, size and mtime into one hit.
+func FindInProfile(profileName string, pattern string, filter FindFilter, config AppConfig, executor Executor) ([]FindHit, error) {
+	executable, repository, err := browseRepository(profileName, config)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	if filter.Cadence != "" {
+		tags = append(tags, TagPrefixCadence+filter.Cadence)
+	}
+	snapshots, err := ListSnapshots(executable, repository, tags, executor)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Since != "" {
+		since, err := time.ParseInLocation("2006-01-02", filter.Since, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", filter.Since)
+		}
+		kept := make([]Snapshot, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			if !snapshot.Time.Before(since) {
+				kept = append(kept, snapshot)
+			}
+		}
+		snapshots = kept
+	}
+	if len(snapshots) == 0 {
+		return []FindHit{}, nil
+	}
+
+	args := []string{"-r", repository, "find", "--json"}
+	snapshotsByID := map[string]Snapshot{}
+	for _, snapshot := range snapshots {
+		args = append(args, "--snapshot", snapshot.ID)
+		snapshotsByID[snapshot.ID] = snapshot
+	}
+	output, err := executor.Run(executable, append(args, pattern)...)
+	if err != nil {
+		return nil, fmt.Errorf("find in %s: %w", repository, err)
+	}
+	var results []findSnapshotResult
+	if strings.TrimSpace(output) != "" {
+		if err := json.Unmarshal([]byte(output), &results); err != nil {
+			return nil, fmt.Errorf("parse find output for %s: %w", profileName, err)
+		}
+	}
+
+	hitsByVersion := map[string]*FindHit{}
+	for _, result := range results {
+		snapshot, ok := snapshotsByID[result.Snapshot]
+		if !ok {
+			continue
+		}
+		for _, match := range result.Matches {
+			if match.Type == "dir" {
+				continue
+			}
+			key := fmt.Sprintf("%s\x00%d\x00%d", match.Path, match.Size, match.MTime.UnixNano())
+			hit, exists := hitsByVersion[key]
+			if !exists {
+				hit = &FindHit{Profile: profileName, Path: match.Path, Size: match.Size, ModTime: match.MTime, FirstSeen: snapshot, LastSeen: snapshot}
+				hitsByVersion[key] = hit
+			}
+			if snapshot.Time.Before(hit.FirstSeen.Time) {
+				hit.FirstSeen = snapshot
+			}
+			if snapshot.Time.After(hit.LastSeen.Time) {
+				hit.LastSeen = snapshot
+			}
+			hit.Snapshots++
+		}
+	}
+	hits := make([]FindHit, 0, len(hitsByVersion))
+	for _, hit := range hitsByVersion {
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
+
+// FindAcrossProfiles searches every profile in parallel and orders the hits
+// by path, newest version first. A profile that fails is reported as a
+// warning unless every profile failed.
+func FindAcrossProfiles(profiles []string, pattern string, filter FindFilter, config AppConfig, executor Executor) ([]FindHit, []string, error) {
+	hitsByProfile := make([][]FindHit, len(profiles))
+	errs := make([]error, len(profiles))
+	var waitGroup sync.WaitGroup
+	for index, profileName := range profiles {
+		waitGroup.Add(1)
+		go func(index int, profileName string) {
+			defer waitGroup.Done()
+			hitsByProfile[index], errs[index] = FindInProfile(profileName, pattern, filter, config, executor)
+		}(index, profileName)
+	}
+	waitGroup.Wait()
+
+	hits := make([]FindHit, 0)
+	warnings := make([]string, 0)
+	for index, profileName := range profiles {
+		if errs[index] != nil {
+			warnings = append(warnings, fmt.Sprintf("warning: %s: %v", profileName, errs[index]))
+			continue
+		}
+		hits = append(hits, hitsByProfile[index]...)
+	}
+	if len(warnings) == len(profiles) {
+		return nil, nil, fmt.Errorf("find failed:\n%s", joinLines(warnings))
+	}
+	sort.SliceStable(hits, func(left int, right int) bool {
+		if hits[left].Path != hits[right].Path {
+			return hits[left].Path < hits[right].Path
+		}
+		if !hits[left].LastSeen.Time.Equal(hits[right].LastSeen.Time) {
+			return hits[left].LastSeen.Time.After(hits[right].LastSeen.Time)
+		}
+		return hits[left].Profile < hits[right].Profile
+	})
+	return hits, warnings, nil
+}
+
+func FormatFindHits(hits []FindHit) []string {
+	lines := make([]string, 0, len(hits)*2)
+	for index, hit := range hits {
+		lines = append(lines, fmt.Sprintf("[%d] %s %s  %d bytes, modified %s", index+1, hit.Profile, hit.Path, hit.Size, hit.ModTime.Local().Format("2006-01-02 15:04")))
+		seen := fmt.Sprintf("in %d snapshot(s) from %s to %s", hit.Snapshots, hit.FirstSeen.Time.Local().Format("2006-01-02 15:04"), hit.LastSeen.Time.Local().Format("2006-01-02 15:04"))
+		if cadence := hit.LastSeen.Cadence(); cadence != "" {
+			seen += fmt.Sprintf(" (latest %s, %s)", hit.LastSeen.ShortID, cadence)
+		} else {
+			seen += fmt.Sprintf(" (latest %s)", hit.LastSeen.ShortID)
+		}
+		lines = append(lines, "    "+seen)
+	}
+	return lines
+}
+
+// RestoreCommandForHit is the restore that brings back the newest snapshot's
+// copy of a hit into dir.
+func RestoreCommandForHit(hit FindHit, dir string) Command {
+	return Command{Name: "restore", Target: dir, Profile: hit.Profile, Snapshot: hit.LastSeen.ID, Include: hit.Path}
+}
+
+// FormatFindRestoreHint repeats the find with its filters, so --restore=<n>
+// numbers the same hits.
+func FormatFindRestoreHint(command Command) string {
+	args := []string{"backup", "find", shellQuote(command.Pattern)}
+	if command.Profile != "" {
+		args = append(args, "--profile="+command.Profile)
+	}
+	if command.Cadence != "" {
+		args = append(args, "--cadence="+command.Cadence)
+	}
+	if command.Since != "" {
+		args = append(args, "--since="+command.Since)
+	}
+	return "restore a hit with: " + strings.Join(append(args, "--restore=<n>", "--to=<dir>"), " ")
+}
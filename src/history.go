@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type RunRecord struct {
+	Cadence      string    `json:"cadence"`
+	Profile      string    `json:"profile"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+	SupersededBy string    `json:"superseded_by,omitempty"`
+	ExitCode     int       `json:"exit_code"`
+	Duration     float64   `json:"duration_seconds,omitempty"`
+	DataAdded    int64     `json:"data_added,omitempty"`
+	FilesNew     int64     `json:"files_new,omitempty"`
+	FilesChanged int64     `json:"files_changed,omitempty"`
+	SnapshotID   string    `json:"snapshot_id,omitempty"`
+}
+
+var clock = time.Now
+
+func SetClockForTests(now func() time.Time) {
+	if now == nil {
+		clock = time.Now
+		return
+	}
+	clock = now
+}
+
+func ResolveStateDir() (string, error) {
+	if override := os.Getenv("BACKUP_STATE_DIR"); override != "" {
+		return override, nil
+	}
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		return filepath.Join(xdgState, "backup"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "backup"), nil
+}
+
+func runHistoryPath() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "history.jsonl"), nil
+}
+
+func LoadRunHistory() ([]RunRecord, error) {
+	historyPath, err := runHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RunRecord{}, nil
+		}
+		return nil, fmt.Errorf("open run history: %w", err)
+	}
+	defer file.Close()
+
+	records := make([]RunRecord, 0)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record RunRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("parse run history %s:%d: %w", historyPath, lineNumber, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read run history: %w", err)
+	}
+	return records, nil
+}
+
+func AppendRunHistory(records []RunRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	historyPath, err := runHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	file, err := os.OpenFile(historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open run history: %w", err)
+	}
+	defer file.Close()
+
+	for _, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("encode run record: %w", err)
+		}
+		if _, err := file.Write(append(encoded, '\n')); err != nil {
+			return fmt.Errorf("write run history: %w", err)
+		}
+	}
+	return nil
+}
+
+func runRecordsFromResults(cadence string, startedAt time.Time, results []ExecutionResult) []RunRecord {
+	finishedAt := clock()
+	records := make([]RunRecord, 0, len(results))
+	for _, result := range results {
+		record := RunRecord{Cadence: cadence, Profile: result.Target, StartedAt: startedAt, FinishedAt: finishedAt, Success: result.Err == nil, Duration: result.Duration.Seconds()}
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+			record.ExitCode = exitCodeOf(result.Err)
+		}
+		if summary, ok := ParseBackupSummary(result.Output); ok {
+			record.DataAdded = summary.DataAdded
+			record.FilesNew = summary.FilesNew
+			record.FilesChanged = summary.FilesChanged
+			record.SnapshotID = summary.SnapshotID
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// LastSuccessfulRuns indexes the most recent successful finish time by
+// cadence and then profile.
+func LastSuccessfulRuns(records []RunRecord) map[string]map[string]time.Time {
+	lastRuns := map[string]map[string]time.Time{}
+	for _, record := range records {
+		if !record.Success {
+			continue
+		}
+		if lastRuns[record.Cadence] == nil {
+			lastRuns[record.Cadence] = map[string]time.Time{}
+		}
+		if record.FinishedAt.After(lastRuns[record.Cadence][record.Profile]) {
+			lastRuns[record.Cadence][record.Profile] = record.FinishedAt
+		}
+	}
+	return lastRuns
+}
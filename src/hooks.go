@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	HookPre       = "pre"
+	HookPost      = "post"
+	HookOnFailure = "on_failure"
+)
+
+const defaultHookTimeout = 15 * time.Minute
+
+type HookConfig struct {
+	Pre       CadencePaths
+	Post      CadencePaths
+	OnFailure CadencePaths
+	Timeout   time.Duration
+}
+
+type fileHookConfig struct {
+	Pre       CadencePaths `yaml:"pre"`
+	Post      CadencePaths `yaml:"post"`
+	OnFailure CadencePaths `yaml:"on_failure"`
+	Timeout   string       `yaml:"timeout"`
+}
+
+func loadHookConfig(file fileHookConfig) (HookConfig, error) {
+	timeout := defaultHookTimeout
+	if file.Timeout != "" {
+		parsed, err := time.ParseDuration(file.Timeout)
+		if err != nil || parsed <= 0 {
+			return HookConfig{}, fmt.Errorf("invalid hook timeout: %q", file.Timeout)
+		}
+		timeout = parsed
+	}
+	return HookConfig{Pre: file.Pre, Post: file.Post, OnFailure: file.OnFailure, Timeout: timeout}, nil
+}
+
+func (hooks HookConfig) commands(stage string, cadence string) []string {
+	switch stage {
+	case HookPre:
+		return hooks.Pre.ForCadence(cadence)
+	case HookPost:
+		return hooks.Post.ForCadence(cadence)
+	default:
+		return hooks.OnFailure.ForCadence(cadence)
+	}
+}
+
+func hookEnvironment(stage string, target string, cadence string, profile ProfileConfig, runErr error) []string {
+	env := []string{
+		"BACKUP_HOOK=" + stage,
+		"BACKUP_PROFILE=" + target,
+		"BACKUP_CADENCE=" + cadence,
+		"BACKUP_REPOSITORY=" + profile.RepositoryHint,
+	}
+	switch stage {
+	case HookPost:
+		env = append(env, "BACKUP_RUN_STATUS="+RunStatusSuccess)
+	case HookOnFailure:
+		env = append(env, "BACKUP_RUN_STATUS="+RunStatusFailure)
+		if runErr != nil {
+			env = append(env, "BACKUP_ERROR="+runErr.Error())
+		}
+	}
+	return env
+}
+
+func hookCommand(target string, command string) (string, []string) {
+	if target == "windows" {
+		return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", command}
+	}
+	return "bash", []string{"-c", command}
+}
+
+// runHooks runs the stage's commands in order and stops at the first failure.
+func runHooks(stage string, target string, cadence string, profile ProfileConfig, runErr error, executor Executor) error {
+	options := CommandOptions{Env: hookEnvironment(stage, target, cadence, profile, runErr), Timeout: profile.Hooks.Timeout}
+	executor = targetExecutor(target, executor)
+	for _, command := range profile.Hooks.commands(stage, cadence) {
+		name, args := hookCommand(target, command)
+		if _, err := runWithOptions(executor, options, name, args...); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+// ExecuteResticInvocationsWithHooks wraps each profile's invocation in its
+// pre, post and on_failure hooks. A failing pre hook skips that profile's
+// restic run; post and on_failure hook failures are reported as warnings.
+func ExecuteResticInvocationsWithHooks(invocations []ResticInvocation, cadence string, config AppConfig, executor Executor) []ExecutionResult {
+	return executeInParallel(invocations, func(invocation ResticInvocation) ExecutionResult {
+		profile := config.Profiles[invocation.Target]
+		result := ExecutionResult{Target: invocation.Target}
+		if err := runHooks(HookPre, invocation.Target, cadence, profile, nil, executor); err != nil {
+			result.Err = err
+		} else {
+			resolved, resticExecutor, vssWarnings := resolveVSSExecution(invocation, profile, executor)
+			result = runResticInvocation(resolved, resticExecutor)
+			result.Warnings = append(result.Warnings, vssWarnings...)
+		}
+
+		stage := HookPost
+		if result.Err != nil {
+			stage = HookOnFailure
+		}
+		if err := runHooks(stage, invocation.Target, cadence, profile, result.Err, executor); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("warning: %s: %v", invocation.Target, err))
+		}
+		return result
+	})
+}
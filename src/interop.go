@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+// interopForwardedEnv lists the variables restic.exe reads that cross into
+// Windows when set. A "/p" suffix asks WSL to translate the value as a path.
+var interopForwardedEnv = []string{
+	"RESTIC_REPOSITORY",
+	"RESTIC_REPOSITORY_FILE/p",
+	"RESTIC_PASSWORD",
+	"RESTIC_PASSWORD_FILE/p",
+	"RESTIC_PASSWORD_COMMAND",
+	"RESTIC_CACHE_DIR/p",
+	"RESTIC_COMPRESSION",
+	"RESTIC_PACK_SIZE",
+	"RESTIC_READ_CONCURRENCY",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_DEFAULT_REGION",
+	"B2_ACCOUNT_ID",
+	"B2_ACCOUNT_KEY",
+	"AZURE_ACCOUNT_NAME",
+	"AZURE_ACCOUNT_KEY",
+	"AZURE_ACCOUNT_SAS",
+	"GOOGLE_PROJECT_ID",
+	"GOOGLE_APPLICATION_CREDENTIALS/p",
+}
+
+// InteropExecutor runs Windows executables from WSL: Linux path arguments
+// become Windows paths, the working directory moves onto a Windows drive and
+// restic's variables are forwarded through WSLENV.
+type InteropExecutor struct {
+	Executor   Executor
+	Translator PathTranslator
+	WorkingDir string
+	Environ    func() []string
+}
+
+func NewInteropExecutor(executor Executor) InteropExecutor {
+	translator := pathTranslatorLoader()
+	workingDir := ""
+	if systemDrive, ok := translator.ToWSL(`C:\`); ok {
+		if info, err := os.Stat(systemDrive.Path); err == nil && info.IsDir() {
+			workingDir = systemDrive.Path
+		}
+	}
+	return InteropExecutor{Executor: executor, Translator: translator, WorkingDir: workingDir, Environ: os.Environ}
+}
+
+// targetExecutor routes the windows profile's commands through the interop
+// layer and leaves everything else untouched.
+func targetExecutor(target string, executor Executor) Executor {
+	if target != "windows" {
+		return executor
+	}
+	switch executor.(type) {
+	case InteropExecutor, ElevatedTaskExecutor:
+		return executor
+	}
+	return NewInteropExecutor(executor)
+}
+
+func (executor InteropExecutor) Run(name string, args ...string) (string, error) {
+	return executor.RunWithOptions(CommandOptions{}, name, args...)
+}
+
+func (executor InteropExecutor) RunWithOptions(options CommandOptions, name string, args ...string) (string, error) {
+	// Linux binaries, such as the WSL-side restic used to browse a Windows
+	// repository, run untouched.
+	if !strings.HasSuffix(strings.ToLower(name), ".exe") {
+		return runWithOptions(executor.Executor, options, name, args...)
+	}
+	environ := []string{}
+	if executor.Environ != nil {
+		environ = executor.Environ()
+	}
+	prepared, translatedArgs := PrepareInteropCommand(options, args, executor.toWindows, executor.WorkingDir, environ)
+	output, err := runWithOptions(executor.Executor, prepared, name, translatedArgs...)
+	var exitError *exec.ExitError
+	if err != nil && errors.As(err, &exitError) {
+		return output, fmt.Errorf("%s exited with code %d: %w", name, exitError.ExitCode(), err)
+	}
+	return output, err
+}
+
+// toWindows prefers wslpath, which knows every mount, and falls back to the
+// mount table for paths that do not exist yet, such as a restore target.
+func (executor InteropExecutor) toWindows(linuxPath string) (string, bool) {
+	if output, err := executor.Executor.Run("wslpath", "-w", linuxPath); err == nil && strings.TrimSpace(output) != "" {
+		return strings.TrimSpace(output), true
+	}
+	return executor.Translator.ToWindows(linuxPath)
+}
+
+// PrepareInteropCommand rewrites a command bound for a Windows executable.
+// Absolute Linux paths, standalone or as a --flag=value, are translated; an
+// empty working directory becomes workingDir; and the names of options.Env
+// plus any forwarded restic variables found in environ are added to WSLENV.
+func PrepareInteropCommand(options CommandOptions, args []string, toWindows func(string) (string, bool), workingDir string, environ []string) (CommandOptions, []string) {
+	translatedArgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		translatedArgs = append(translatedArgs, translateInteropArg(arg, toWindows))
+	}
+
+	prepared := options
+	if prepared.Dir == "" {
+		prepared.Dir = workingDir
+	}
+
+	existing := lookupEnv(environ, "WSLENV")
+	env := make([]string, 0, len(options.Env)+1)
+	names := make([]string, 0)
+	for _, entry := range options.Env {
+		name, value, _ := strings.Cut(entry, "=")
+		if name == "WSLENV" {
+			existing = value
+			continue
+		}
+		env = append(env, entry)
+		if isLinuxAbsolutePath(value) {
+			name += "/p"
+		}
+		names = append(names, name)
+	}
+	for _, forwarded := range interopForwardedEnv {
+		name, _, _ := strings.Cut(forwarded, "/")
+		if lookupEnv(environ, name) != "" {
+			names = append(names, forwarded)
+		}
+	}
+	prepared.Env = env
+	if len(names) > 0 {
+		prepared.Env = append(prepared.Env, "WSLENV="+withWSLENV(existing, names))
+	} else if existing != lookupEnv(environ, "WSLENV") {
+		prepared.Env = append(prepared.Env, "WSLENV="+existing)
+	}
+	return prepared, translatedArgs
+}
+
+func translateInteropArg(arg string, toWindows func(string) (string, bool)) string {
+	if isLinuxAbsolutePath(arg) {
+		if translated, ok := toWindows(arg); ok {
+			return translated
+		}
+		return arg
+	}
+	if flag, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(flag, "--") && isLinuxAbsolutePath(value) {
+		if translated, ok := toWindows(value); ok {
+			return flag + "=" + translated
+		}
+	}
+	return arg
+}
+
+func isLinuxAbsolutePath(value string) bool {
+	return strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "//")
+}
+
+func lookupEnv(environ []string, name string) string {
+	value := ""
+	for _, entry := range environ {
+		if key, entryValue, found := strings.Cut(entry, "="); found && key == name {
+			value = entryValue
+		}
+	}
+	return value
+}
+
+// withWSLENV appends names to an existing WSLENV value, skipping names that
+// are already listed with or without flags.
+func withWSLENV(existing string, names []string) string {
+	entries := make([]string, 0, len(names)+1)
+	listed := map[string]bool{}
+	if existing != "" {
+		for _, entry := range strings.Split(existing, ":") {
+			name, _, _ := strings.Cut(entry, "/")
+			listed[name] = true
+		}
+		entries = append(entries, existing)
+	}
+	for _, entry := range names {
+		name, _, _ := strings.Cut(entry, "/")
+		if listed[name] {
+			continue
+		}
+		listed[name] = true
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, ":")
+}
+
+// DecodeConsoleOutput turns the UTF-16LE text some Windows tools write when
+// their output is redirected into UTF-8, and normalizes CRLF line endings.
+func DecodeConsoleOutput(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFF && raw[1] == 0xFE {
+		raw = []byte(decodeUTF16LE(raw[2:]))
+	} else if looksLikeUTF16LE(raw) {
+		raw = []byte(decodeUTF16LE(raw))
+	}
+	return strings.ReplaceAll(string(raw), "\r\n", "\n")
+}
+
+// looksLikeUTF16LE spots BOM-less UTF-16LE: mostly-ASCII text leaves a zero
+// in nearly every odd byte, which never happens in UTF-8 text.
+func looksLikeUTF16LE(raw []byte) bool {
+	if len(raw) < 2 {
+		return false
+	}
+	zeroOdd := 0
+	for index := 1; index < len(raw); index += 2 {
+		if raw[index] == 0 {
+			zeroOdd++
+		}
+	}
+	return zeroOdd*4 >= len(raw)/2*3
+}
+
+func decodeUTF16LE(raw []byte) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for index := 0; index+1 < len(raw); index += 2 {
+		units = append(units, uint16(raw[index])|uint16(raw[index+1])<<8)
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,361 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+type RuleSeverity string
+
+const (
+	SeverityError   RuleSeverity = "error"
+	SeverityWarning RuleSeverity = "warning"
+	SeverityInfo    RuleSeverity = "info"
+)
+
+type RuleFinding struct {
+	Severity RuleSeverity
+	Profile  string
+	Message  string
+}
+
+func LintRules(plan RunPlan, config AppConfig) []RuleFinding {
+	findings := make([]RuleFinding, 0)
+	overlaps, allowedOverlaps := FilterAllowedOverlaps(FindPlatformIncludeOverlaps(plan, config), config)
+	// Overlaps follow the configured policy so lint agrees with what run
+	// would do: only strict treats them as errors.
+	overlapSeverity := SeverityError
+	overlapPrefix := ""
+	switch ResolveOverlapPolicy("", config) {
+	case OverlapPolicyWarn:
+		overlapSeverity = SeverityWarning
+	case OverlapPolicyOff:
+		overlapSeverity = SeverityInfo
+		overlapPrefix = "accepted overlap (overlap_policy off): "
+	case OverlapPolicyDedupe:
+		overlapSeverity = SeverityInfo
+		overlapPrefix = "deduplicated overlap: "
+	}
+	for _, overlap := range overlaps {
+		findings = append(findings, RuleFinding{
+			Severity: overlapSeverity,
+			Profile:  overlap.LeftTarget + "," + overlap.RightTarget,
+			Message:  overlapPrefix + fmt.Sprintf("include %s=%s overlaps %s=%s", overlap.LeftTarget, overlap.LeftPath, overlap.RightTarget, overlap.RightPath),
+		})
+	}
+	for _, include := range FindUncheckableIncludes(plan, config) {
+		findings = append(findings, RuleFinding{
+			Severity: SeverityWarning,
+			Profile:  include.Target,
+			Message:  fmt.Sprintf("uncheckable include %s: not an absolute path after expanding variables; overlap check skipped", include.Path),
+		})
+	}
+	for _, overlap := range allowedOverlaps {
+		findings = append(findings, RuleFinding{
+			Severity: SeverityInfo,
+			Profile:  overlap.LeftTarget + "," + overlap.RightTarget,
+			Message:  fmt.Sprintf("allowed overlap: %s=%s overlaps %s=%s", overlap.LeftTarget, overlap.LeftPath, overlap.RightTarget, overlap.RightPath),
+		})
+	}
+
+	translator := pathTranslatorLoader()
+	for _, target := range plan.Targets {
+		profile, ok := config.Profiles[target]
+		if !ok {
+			continue
+		}
+		findings = append(findings, lintProfileRules(target, plan.Cadence, profile, translator)...)
+	}
+	return findings
+}
+
+type lintInclude struct {
+	raw        string
+	translated TranslatedPath
+}
+
+func lintProfileRules(target string, cadence string, profile ProfileConfig, translator PathTranslator) []RuleFinding {
+	findings := make([]RuleFinding, 0)
+
+	includes := make([]lintInclude, 0)
+	for _, includePath := range profile.IncludeByCadence.ForCadence(cadence) {
+		if translated, ok := translator.ToWSL(includePath); ok {
+			includes = append(includes, lintInclude{raw: includePath, translated: translated})
+		}
+	}
+
+	for outerIndex, outer := range includes {
+		for innerIndex, inner := range includes {
+			if outerIndex == innerIndex || outer.translated.Key == inner.translated.Key {
+				continue
+			}
+			if isSameOrParentPath(outer.translated.Key, inner.translated.Key) {
+				findings = append(findings, RuleFinding{
+					Severity: SeverityWarning,
+					Profile:  target,
+					Message:  fmt.Sprintf("include %s is already covered by include %s", inner.raw, outer.raw),
+				})
+			}
+		}
+	}
+
+	excludes := compileExcludeRules(profile.ExcludeByCadence.ForCadence(cadence), translator)
+	for _, include := range includes {
+		index := excludingRuleIndex(excludes, include.translated)
+		if index < 0 || excludes.reincludeBelow(index, include.translated) {
+			continue
+		}
+		findings = append(findings, RuleFinding{
+			Severity: SeverityError,
+			Profile:  target,
+			Message:  fmt.Sprintf("exclude %s removes the entire include root %s", excludes[index].raw, include.raw),
+		})
+	}
+
+	for _, exclude := range excludes {
+		if exclude.negated || !exclude.absolute {
+			continue
+		}
+		prefix := literalPatternPrefix(exclude.pattern)
+		reachable := false
+		for _, include := range includes {
+			if pathsOverlap(prefix, include.translated.Key) {
+				reachable = true
+				break
+			}
+		}
+		if !reachable {
+			findings = append(findings, RuleFinding{
+				Severity: SeverityWarning,
+				Profile:  target,
+				Message:  fmt.Sprintf("exclude %s matches nothing under any include root", exclude.raw),
+			})
+		}
+	}
+
+	findings = append(findings, lintDuplicateRules(target, cadence, profile)...)
+	return findings
+}
+
+func lintDuplicateRules(target string, cadence string, profile ProfileConfig) []RuleFinding {
+	type duplicateKey struct {
+		kind string
+		path string
+	}
+
+	rules := profile.Rules
+	if len(rules) == 0 {
+		rules = append(inlineRuleEntries(profile.IncludeByCadence, RuleKindInclude, ""), inlineRuleEntries(profile.ExcludeByCadence, RuleKindExclude, "")...)
+	}
+
+	order := make([]duplicateKey, 0)
+	grouped := map[duplicateKey][]RuleEntry{}
+	for _, rule := range rules {
+		if rule.Cadence != cadence {
+			continue
+		}
+		key := duplicateKey{kind: rule.Kind, path: rule.Path}
+		if _, exists := grouped[key]; !exists {
+			order = append(order, key)
+		}
+		grouped[key] = append(grouped[key], rule)
+	}
+
+	findings := make([]RuleFinding, 0)
+	for _, key := range order {
+		entries := grouped[key]
+		if len(entries) < 2 {
+			continue
+		}
+		origins := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			origins = append(origins, describeRuleOrigin(entry))
+		}
+		findings = append(findings, RuleFinding{
+			Severity: SeverityInfo,
+			Profile:  target,
+			Message:  fmt.Sprintf("duplicate %s %s (%s)", key.kind, key.path, strings.Join(origins, "; ")),
+		})
+	}
+	return findings
+}
+
+func describeRuleOrigin(rule RuleEntry) string {
+	switch {
+	case rule.Origin == RuleOriginPreset:
+		return "preset " + rule.Source
+	case rule.Source != "":
+		return rule.Origin + " " + rule.Source
+	case rule.Origin != "":
+		return rule.Origin
+	default:
+		return "config"
+	}
+}
+
+func literalPatternPrefix(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	literal := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		literal = append(literal, segment)
+	}
+	prefix := strings.Join(literal, "/")
+	if prefix == "" {
+		return "/"
+	}
+	return prefix
+}
+
+// excludeRule is one exclude in the form paths are compared in: absolute
+// patterns, Windows ones included, become a WSL path key. A negated ("!")
+// rule re-includes what earlier rules excluded.
+type excludeRule struct {
+	raw      string
+	pattern  string
+	absolute bool
+	negated  bool
+}
+
+type excludeRules []excludeRule
+
+func compileExcludeRules(excludes []string, translator PathTranslator) excludeRules {
+	rules := make(excludeRules, 0, len(excludes))
+	for _, exclude := range excludes {
+		rule := excludeRule{raw: exclude}
+		pattern := exclude
+		if strings.HasPrefix(pattern, "!") {
+			rule.negated = true
+			pattern = strings.TrimPrefix(pattern, "!")
+		}
+		slashed := strings.ReplaceAll(pattern, `\`, "/")
+		rule.pattern = slashed
+		if strings.HasPrefix(slashed, "/") || isDrivePath(slashed) {
+			translated, ok := translator.ToWSL(pattern)
+			if !ok {
+				continue
+			}
+			rule.absolute = true
+			rule.pattern = translated.Key
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (rule excludeRule) matches(target TranslatedPath) bool {
+	pattern := rule.pattern
+	if !rule.absolute && target.WindowsBacked {
+		pattern = strings.ToLower(pattern)
+	}
+	return resticPatternMatchesPathOrParent(pattern, target.Key)
+}
+
+// excludingRuleIndex applies the rules in order, as restic does: the last
+// rule matching target (or a parent) decides, and a negated one keeps it.
+// It returns the index of the excluding rule, or -1.
+func excludingRuleIndex(rules excludeRules, target TranslatedPath) int {
+	index := -1
+	for candidate, rule := range rules {
+		if rule.matches(target) {
+			index = candidate
+			if rule.negated {
+				index = -1
+			}
+		}
+	}
+	return index
+}
+
+// reincludeBelow reports whether a negated rule after index may re-include
+// something below target, so restic still descends into it.
+func (rules excludeRules) reincludeBelow(index int, target TranslatedPath) bool {
+	for _, rule := range rules[index+1:] {
+		if !rule.negated {
+			continue
+		}
+		if !rule.absolute || pathsOverlap(literalPatternPrefix(rule.pattern), target.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// resticPatternMatchesPathOrParent mirrors restic's exclude matching: a
+// pattern without a leading slash matches at any depth, "**" spans segments,
+// and excluding a parent directory excludes everything below it.
+func resticPatternMatchesPathOrParent(pattern string, target string) bool {
+	patternSegments := splitPathSegments(pattern)
+	if !strings.HasPrefix(pattern, "/") {
+		patternSegments = append([]string{"**"}, patternSegments...)
+	}
+	targetSegments := splitPathSegments(target)
+	for length := len(targetSegments); length >= 1; length-- {
+		if matchPatternSegments(patternSegments, targetSegments[:length]) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPathSegments(value string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(value, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func matchPatternSegments(pattern []string, target []string) bool {
+	if len(pattern) == 0 {
+		return len(target) == 0
+	}
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(target); skip++ {
+			if matchPatternSegments(pattern[1:], target[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(target) == 0 {
+		return false
+	}
+	if matched, err := path.Match(pattern[0], target[0]); err != nil || !matched {
+		return false
+	}
+	return matchPatternSegments(pattern[1:], target[1:])
+}
+
+func FormatRuleFindings(findings []RuleFinding) string {
+	if len(findings) == 0 {
+		return "no rule findings"
+	}
+	severityRank := map[RuleSeverity]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+	sorted := append([]RuleFinding{}, findings...)
+	sort.SliceStable(sorted, func(left int, right int) bool {
+		return severityRank[sorted[left].Severity] < severityRank[sorted[right].Severity]
+	})
+
+	lines := make([]string, 0, len(sorted))
+	for _, finding := range sorted {
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", finding.Severity, finding.Profile, finding.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func countRuleFindings(findings []RuleFinding, severity RuleSeverity) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
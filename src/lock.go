@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var lockPollInterval = 200 * time.Millisecond
+
+type InstanceLock struct {
+	path string
+	file *os.File
+}
+
+type lockHolder struct {
+	PID     int
+	Since   string
+	Command string
+}
+
+// ResolveLockPath keeps the lock in the state dir rather than
+// $XDG_RUNTIME_DIR: on WSL, WSLg shells set the runtime dir to
+// /mnt/wslg/runtime-dir while systemd user timers get /run/user/<uid>, so
+// manual and scheduled runs would lock different files.
+func ResolveLockPath() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "backup.lock"), nil
+}
+
+// AcquireInstanceLock takes the single-instance lock for commands that touch
+// restic repositories. The lock file is never deleted; the kernel lock on it
+// is what counts, so a crashed holder never leaves a stale lock behind.
+func AcquireInstanceLock(description string, wait bool) (*InstanceLock, error) {
+	lockPath, err := ResolveLockPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("lock %s: %w", lockPath, err)
+		}
+		if locked {
+			break
+		}
+		if !wait {
+			_ = file.Close()
+			return nil, fmt.Errorf("%s; use --wait to queue behind it", describeLockHolder(lockPath))
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	holder := fmt.Sprintf("pid=%d\nsince=%s\ncommand=%s\n", os.Getpid(), clock().Format(time.RFC3339), description)
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(holder), 0)
+	}
+	return &InstanceLock{path: lockPath, file: file}, nil
+}
+
+func (lock *InstanceLock) Release() {
+	if lock == nil || lock.file == nil {
+		return
+	}
+	_ = lock.file.Truncate(0)
+	_ = unlockFile(lock.file)
+	_ = lock.file.Close()
+	lock.file = nil
+}
+
+func describeLockHolder(lockPath string) string {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "another backup is running"
+	}
+	holder := parseLockHolder(string(data))
+	if holder.PID == 0 {
+		return "another backup is running"
+	}
+	message := fmt.Sprintf("another backup is running since %s (pid %d", holder.Since, holder.PID)
+	if holder.Command != "" {
+		message += ", " + holder.Command
+	}
+	return message + ")"
+}
+
+func parseLockHolder(content string) lockHolder {
+	holder := lockHolder{}
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pid":
+			holder.PID, _ = strconv.Atoi(value)
+		case "since":
+			holder.Since = value
+		case "command":
+			holder.Command = value
+		}
+	}
+	return holder
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package backup
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	errorLockViolation      = syscall.Errno(33)
+)
+
+func tryLockFile(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	procedure := syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+	result, _, callErr := procedure.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result != 0 {
+		return true, nil
+	}
+	if errors.Is(callErr, errorLockViolation) {
+		return false, nil
+	}
+	return false, callErr
+}
+
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	procedure := syscall.NewLazyDLL("kernel32.dll").NewProc("UnlockFileEx")
+	result, _, callErr := procedure.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result == 0 {
+		return callErr
+	}
+	return nil
+}
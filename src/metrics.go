@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type BackupSummary struct {
+	FilesNew      int64   `json:"files_new"`
+	FilesChanged  int64   `json:"files_changed"`
+	DataAdded     int64   `json:"data_added"`
+	TotalDuration float64 `json:"total_duration"`
+	SnapshotID    string  `json:"snapshot_id"`
+}
+
+type MetricsConfig struct {
+	Textfile string
+}
+
+type fileMetricsConfig struct {
+	Textfile string `yaml:"textfile"`
+}
+
+// ParseBackupSummary reads the final summary message from `restic backup
+// --json` output, skipping status lines and any stderr text mixed in.
+func ParseBackupSummary(output string) (BackupSummary, bool) {
+	lines := strings.Split(output, "\n")
+	for index := len(lines) - 1; index >= 0; index-- {
+		line := strings.TrimSpace(lines[index])
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var message struct {
+			MessageType string `json:"message_type"`
+			BackupSummary
+		}
+		if err := json.Unmarshal([]byte(line), &message); err != nil || message.MessageType != "summary" {
+			continue
+		}
+		return message.BackupSummary, true
+	}
+	return BackupSummary{}, false
+}
+
+// exitCodeOf reads the exit status from process errors, including those the
+// elevated task runner reports back.
+func exitCodeOf(err error) int {
+	var exitError interface{ ExitCode() int }
+	if errors.As(err, &exitError) {
+		return exitError.ExitCode()
+	}
+	return 1
+}
+
+type metricSeries struct {
+	name  string
+	help  string
+	value func(last RunRecord, lastSuccess RunRecord, hasSuccess bool) (float64, bool)
+}
+
+var metricSeriesList = []metricSeries{
+	{"backup_last_success_timestamp_seconds", "Unix time of the last successful backup run.", func(_ RunRecord, lastSuccess RunRecord, hasSuccess bool) (float64, bool) {
+		return float64(lastSuccess.FinishedAt.Unix()), hasSuccess
+	}},
+	{"backup_last_run_timestamp_seconds", "Unix time the last backup run finished.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return float64(last.FinishedAt.Unix()), true
+	}},
+	{"backup_last_run_success", "Whether the last backup run succeeded (1) or failed (0).", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		if last.Success {
+			return 1, true
+		}
+		return 0, true
+	}},
+	{"backup_last_run_exit_status", "Exit status of restic in the last backup run.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return float64(last.ExitCode), true
+	}},
+	{"backup_last_run_duration_seconds", "Duration of the restic invocation in the last backup run.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return last.Duration, true
+	}},
+	{"backup_last_run_data_added_bytes", "Bytes added to the repository by the last backup run.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return float64(last.DataAdded), true
+	}},
+	{"backup_last_run_files_new", "New files stored by the last backup run.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return float64(last.FilesNew), true
+	}},
+	{"backup_last_run_files_changed", "Changed files stored by the last backup run.", func(last RunRecord, _ RunRecord, _ bool) (float64, bool) {
+		return float64(last.FilesChanged), true
+	}},
+}
+
+// FormatPrometheusMetrics renders the latest run per profile and cadence in
+// the Prometheus text exposition format.
+func FormatPrometheusMetrics(records []RunRecord) string {
+	type seriesKey struct {
+		profile string
+		cadence string
+	}
+	lastRuns := map[seriesKey]RunRecord{}
+	lastSuccesses := map[seriesKey]RunRecord{}
+	for _, record := range records {
+		key := seriesKey{profile: record.Profile, cadence: record.Cadence}
+		if current, ok := lastRuns[key]; !ok || !record.FinishedAt.Before(current.FinishedAt) {
+			lastRuns[key] = record
+		}
+		if !record.Success {
+			continue
+		}
+		if current, ok := lastSuccesses[key]; !ok || !record.FinishedAt.Before(current.FinishedAt) {
+			lastSuccesses[key] = record
+		}
+	}
+
+	keys := make([]seriesKey, 0, len(lastRuns))
+	for key := range lastRuns {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(left int, right int) bool {
+		if keys[left].profile != keys[right].profile {
+			return keys[left].profile < keys[right].profile
+		}
+		return keys[left].cadence < keys[right].cadence
+	})
+
+	lines := make([]string, 0)
+	for _, series := range metricSeriesList {
+		lines = append(lines, fmt.Sprintf("# HELP %s %s", series.name, series.help))
+		lines = append(lines, fmt.Sprintf("# TYPE %s gauge", series.name))
+		for _, key := range keys {
+			lastSuccess, hasSuccess := lastSuccesses[key]
+			value, ok := series.value(lastRuns[key], lastSuccess, hasSuccess)
+			if !ok {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s{profile=%q,cadence=%q} %s", series.name, key.profile, key.cadence, formatMetricValue(value)))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func formatMetricValue(value float64) string {
+	if value == float64(int64(value)) {
+		return fmt.Sprintf("%d", int64(value))
+	}
+	return fmt.Sprintf("%g", value)
+}
+
+// WriteMetricsTextfile replaces the collector file atomically so
+// node_exporter never scrapes a half-written file.
+func WriteMetricsTextfile(path string, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create metrics dir: %w", err)
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(path), ".backup-metrics-*")
+	if err != nil {
+		return fmt.Errorf("create metrics temp file: %w", err)
+	}
+	if _, err := temporary.WriteString(content); err != nil {
+		_ = temporary.Close()
+		_ = os.Remove(temporary.Name())
+		return fmt.Errorf("write metrics: %w", err)
+	}
+	if err := temporary.Close(); err != nil {
+		_ = os.Remove(temporary.Name())
+		return fmt.Errorf("write metrics: %w", err)
+	}
+	if err := os.Chmod(temporary.Name(), 0o644); err != nil {
+		_ = os.Remove(temporary.Name())
+		return fmt.Errorf("write metrics: %w", err)
+	}
+	if err := os.Rename(temporary.Name(), path); err != nil {
+		_ = os.Remove(temporary.Name())
+		return fmt.Errorf("replace metrics file: %w", err)
+	}
+	return nil
+}
+
+func updateMetricsTextfile(config MetricsConfig) error {
+	if config.Textfile == "" {
+		return nil
+	}
+	records, err := LoadRunHistory()
+	if err != nil {
+		return err
+	}
+	return WriteMetricsTextfile(config.Textfile, FormatPrometheusMetrics(records))
+}
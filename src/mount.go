@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+var foregroundRunner = runForeground
+
+func SetForegroundRunnerForTests(runner func(name string, args ...string) error) {
+	if runner == nil {
+		foregroundRunner = runForeground
+		return
+	}
+	foregroundRunner = runner
+}
+
+// runForeground attaches the command to the terminal and waits for it. The
+// terminal delivers Ctrl-C to restic directly; SIGTERM from kill or systemd
+// is forwarded so restic still unmounts. Either way this process keeps
+// running until restic has exited.
+func runForeground(name string, args ...string) error {
+	command := exec.Command(name, args...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := command.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- command.Wait() }()
+
+	interrupted := false
+	for {
+		select {
+		case err := <-done:
+			if interrupted {
+				return nil
+			}
+			return err
+		case received := <-signals:
+			interrupted = true
+			if received != os.Interrupt {
+				_ = command.Process.Signal(received)
+			}
+		}
+	}
+}
+
+// RunMount mounts a profile's repository with restic's FUSE mount and blocks
+// until it is unmounted. The selected snapshot's path is written to progress
+// before blocking.
+func RunMount(command Command, config AppConfig, executor Executor, progress io.Writer) (string, error) {
+	profileName := command.Profile
+	if profileName == "" {
+		profileName = "wsl"
+	}
+	executable, repository, err := browseRepository(profileName, config)
+	if err != nil {
+		return "", err
+	}
+	if check := CheckResticVersion("wsl", executable, config, executor); check.Err != nil {
+		return "", fmt.Errorf("restic preflight failed: %w", check.Err)
+	}
+
+	snapshots, err := ListSnapshots(executable, repository, nil, executor)
+	if err != nil {
+		return "", err
+	}
+	snapshot, found, err := SelectSnapshot(snapshots, command.Cadence, command.Date)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no %s snapshot matches%s", profileName, describeSnapshotFilter(command.Cadence, command.Date))
+	}
+
+	mountPoint, err := filepath.Abs(command.Target)
+	if err != nil {
+		return "", fmt.Errorf("resolve mountpoint: %w", err)
+	}
+	if err := os.MkdirAll(mountPoint, 0o755); err != nil {
+		return "", fmt.Errorf("create mountpoint: %w", err)
+	}
+
+	cadence := snapshot.Cadence()
+	if cadence == "" {
+		cadence = "untagged"
+	}
+	_, _ = fmt.Fprintf(progress, "mounting %s repository %s at %s (Ctrl-C to unmount)\n", profileName, repository, mountPoint)
+	_, _ = fmt.Fprintf(progress, "snapshot %s (%s, %s): %s\n", snapshot.ShortID, snapshot.Time.Local().Format("2006-01-02 15:04"), cadence, filepath.Join(mountPoint, "ids", snapshot.ShortID))
+
+	runErr := foregroundRunner(executable, "-r", repository, "mount", mountPoint)
+	if isMountPoint(mountPoint) {
+		if _, err := executor.Run("fusermount", "-u", mountPoint); err != nil {
+			return "", fmt.Errorf("restic exited but %s is still mounted; run fusermount -u %s: %w", mountPoint, mountPoint, err)
+		}
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("restic mount failed: %w", runErr)
+	}
+	return fmt.Sprintf("unmounted %s", mountPoint), nil
+}
+
+func describeSnapshotFilter(cadence string, date string) string {
+	if cadence == "" && date == "" {
+		return " (repository has no snapshots)"
+	}
+	description := ""
+	if cadence != "" {
+		description += " cadence " + cadence
+	}
+	if date != "" {
+		description += " on or before " + date
+	}
+	return description
+}
+
+func isMountPoint(mountPoint string) bool {
+	procMounts, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return false
+	}
+	for _, entry := range ParseProcMounts(string(procMounts)) {
+		if entry.MountPoint == mountPoint {
+			return true
+		}
+	}
+	return false
+}
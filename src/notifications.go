@@ -0,0 +1,258 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	NotifyOnFailure = "failure"
+	NotifyOnWarning = "warning"
+	NotifyOnAlways  = "always"
+
+	RunStatusSuccess = "success"
+	RunStatusWarning = "warning"
+	RunStatusFailure = "failure"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// powershell.exe is a registered AppUserModelID, so toasts raised under it are
+// shown without registering a shortcut for this tool.
+const toastAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+type NotificationConfig struct {
+	On         string
+	Toast      bool
+	NotifySend bool
+	Webhook    WebhookConfig
+	Email      EmailDigestConfig
+}
+
+type WebhookConfig struct {
+	URL     string
+	On      string
+	Headers map[string]string
+	Timeout time.Duration
+}
+
+type fileNotificationConfig struct {
+	On         string                `yaml:"on"`
+	Toast      bool                  `yaml:"toast"`
+	NotifySend bool                  `yaml:"notify_send"`
+	Webhook    fileWebhookConfig     `yaml:"webhook"`
+	Email      fileEmailDigestConfig `yaml:"email"`
+}
+
+type fileWebhookConfig struct {
+	URL     string            `yaml:"url"`
+	On      string            `yaml:"on"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout string            `yaml:"timeout"`
+}
+
+type RunSummary struct {
+	Cadence    string          `json:"cadence"`
+	Status     string          `json:"status"`
+	Host       string          `json:"host"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Targets    []TargetSummary `json:"targets"`
+	Warnings   []string        `json:"warnings,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type TargetSummary struct {
+	Target          string  `json:"target"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+func isValidNotifyOn(trigger string) bool {
+	switch trigger {
+	case NotifyOnFailure, NotifyOnWarning, NotifyOnAlways:
+		return true
+	default:
+		return false
+	}
+}
+
+func loadNotificationConfig(file fileNotificationConfig, configDir string) (NotificationConfig, error) {
+	for _, trigger := range []string{file.On, file.Webhook.On} {
+		if trigger != "" && !isValidNotifyOn(trigger) {
+			return NotificationConfig{}, fmt.Errorf("invalid notification trigger %q (expected failure, warning or always)", trigger)
+		}
+	}
+	timeout := defaultWebhookTimeout
+	if file.Webhook.Timeout != "" {
+		parsed, err := time.ParseDuration(file.Webhook.Timeout)
+		if err != nil || parsed <= 0 {
+			return NotificationConfig{}, fmt.Errorf("invalid webhook timeout: %q", file.Webhook.Timeout)
+		}
+		timeout = parsed
+	}
+	if file.Webhook.URL != "" && !strings.HasPrefix(file.Webhook.URL, "http://") && !strings.HasPrefix(file.Webhook.URL, "https://") {
+		return NotificationConfig{}, fmt.Errorf("invalid webhook url: %q", file.Webhook.URL)
+	}
+
+	email, err := loadEmailDigestConfig(file.Email, configDir)
+	if err != nil {
+		return NotificationConfig{}, err
+	}
+
+	on := file.On
+	if on == "" {
+		on = NotifyOnFailure
+	}
+	webhookOn := file.Webhook.On
+	if webhookOn == "" {
+		webhookOn = on
+	}
+	return NotificationConfig{
+		On:         on,
+		Toast:      file.Toast,
+		NotifySend: file.NotifySend,
+		Webhook: WebhookConfig{
+			URL:     file.Webhook.URL,
+			On:      webhookOn,
+			Headers: file.Webhook.Headers,
+			Timeout: timeout,
+		},
+		Email: email,
+	}, nil
+}
+
+func BuildRunSummary(cadence string, startedAt time.Time, finishedAt time.Time, results []ExecutionResult, warnings []string) RunSummary {
+	host, _ := os.Hostname()
+	summary := RunSummary{
+		Cadence:    cadence,
+		Status:     RunStatusSuccess,
+		Host:       host,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Targets:    make([]TargetSummary, 0, len(results)),
+		Warnings:   warnings,
+	}
+	if len(warnings) > 0 {
+		summary.Status = RunStatusWarning
+	}
+	for _, result := range results {
+		target := TargetSummary{Target: result.Target, Status: RunStatusSuccess, DurationSeconds: result.Duration.Seconds()}
+		if result.Err != nil {
+			target.Status = RunStatusFailure
+			target.Error = result.Err.Error()
+			summary.Status = RunStatusFailure
+		}
+		summary.Targets = append(summary.Targets, target)
+	}
+	return summary
+}
+
+func shouldNotify(trigger string, status string) bool {
+	switch trigger {
+	case NotifyOnAlways:
+		return true
+	case NotifyOnWarning:
+		return status == RunStatusWarning || status == RunStatusFailure
+	default:
+		return status == RunStatusFailure
+	}
+}
+
+// SendRunNotifications delivers the summary to every configured channel
+// whose trigger matches and returns one warning line per failed delivery.
+func SendRunNotifications(config NotificationConfig, summary RunSummary, executor Executor) []string {
+	warnings := make([]string, 0)
+	title, body := formatNotificationText(summary)
+
+	if config.Toast && shouldNotify(config.On, summary.Status) {
+		if _, err := executor.Run("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", buildToastScript(title, body)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("warning: toast notification failed: %v", err))
+		}
+	}
+	if config.NotifySend && shouldNotify(config.On, summary.Status) {
+		urgency := "normal"
+		if summary.Status == RunStatusFailure {
+			urgency = "critical"
+		}
+		if _, err := executor.Run("notify-send", "--app-name=backup", "--urgency="+urgency, title, body); err != nil {
+			warnings = append(warnings, fmt.Sprintf("warning: notify-send notification failed: %v", err))
+		}
+	}
+	if config.Webhook.URL != "" && shouldNotify(config.Webhook.On, summary.Status) {
+		if err := postWebhook(config.Webhook, summary); err != nil {
+			warnings = append(warnings, fmt.Sprintf("warning: webhook notification failed: %v", err))
+		}
+	}
+	return warnings
+}
+
+func formatNotificationText(summary RunSummary) (string, string) {
+	title := fmt.Sprintf("backup %s run succeeded", summary.Cadence)
+	switch summary.Status {
+	case RunStatusFailure:
+		title = fmt.Sprintf("backup %s run failed", summary.Cadence)
+	case RunStatusWarning:
+		title = fmt.Sprintf("backup %s run completed with warnings", summary.Cadence)
+	}
+
+	lines := make([]string, 0, len(summary.Targets)+1)
+	if summary.Error != "" {
+		lines = append(lines, summary.Error)
+	}
+	for _, target := range summary.Targets {
+		line := fmt.Sprintf("%s: %s (%s)", target.Target, target.Status, time.Duration(target.DurationSeconds*float64(time.Second)).Round(time.Second))
+		if target.Error != "" {
+			line += ": " + target.Error
+		}
+		lines = append(lines, line)
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+func buildToastScript(title string, body string) string {
+	quote := func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return strings.Join([]string{
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null",
+		"$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+		"$texts = $template.GetElementsByTagName('text')",
+		fmt.Sprintf("$texts.Item(0).AppendChild($template.CreateTextNode(%s)) > $null", quote(title)),
+		fmt.Sprintf("$texts.Item(1).AppendChild($template.CreateTextNode(%s)) > $null", quote(body)),
+		"$toast = [Windows.UI.Notifications.ToastNotification]::new($template)",
+		fmt.Sprintf("[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(%s).Show($toast)", quote(toastAppID)),
+	}, "; ")
+}
+
+func postWebhook(config WebhookConfig, summary RunSummary) error {
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range config.Headers {
+		request.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	client := &http.Client{Timeout: config.Timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
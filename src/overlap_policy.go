@@ -0,0 +1,195 @@
+package backup
+
+import "fmt"
+
+const (
+	OverlapPolicyStrict = "strict"
+	OverlapPolicyWarn   = "warn"
+	OverlapPolicyOff    = "off"
+	OverlapPolicyDedupe = "dedupe"
+
+	OverlapWinnerAuto = "auto"
+)
+
+// OverlapAssignment records which profile owns an overlapping subtree in
+// dedupe mode. The losing profile either drops an include that lies entirely
+// inside the subtree or excludes the subtree from a wider include.
+type OverlapAssignment struct {
+	Owner          string
+	SharedPath     string
+	Loser          string
+	Exclude        string
+	DroppedInclude string
+}
+
+func isValidOverlapPolicy(policy string) bool {
+	switch policy {
+	case OverlapPolicyStrict, OverlapPolicyWarn, OverlapPolicyOff, OverlapPolicyDedupe:
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidOverlapWinner(winner string) bool {
+	switch winner {
+	case OverlapWinnerAuto, "wsl", "windows":
+		return true
+	default:
+		return false
+	}
+}
+
+func ResolveOverlapPolicy(override string, config AppConfig) string {
+	if override != "" {
+		return override
+	}
+	if config.OverlapPolicy != "" {
+		return config.OverlapPolicy
+	}
+	return OverlapPolicyStrict
+}
+
+// FilterAllowedOverlaps drops overlaps whose shared subtree (the deeper of the
+// two include paths) sits at or below an allowed_overlaps entry of either
+// profile involved.
+func FilterAllowedOverlaps(overlaps []IncludeOverlap, config AppConfig) ([]IncludeOverlap, []IncludeOverlap) {
+	translator := pathTranslatorLoader()
+	allowedKeys := map[string][]string{}
+	for target, profile := range config.Profiles {
+		for _, allowedPath := range profile.AllowedOverlaps {
+			if translated, ok := translator.ToWSL(ExpandPathVariables(allowedPath)); ok {
+				allowedKeys[target] = append(allowedKeys[target], translated.Key)
+			}
+		}
+	}
+
+	remaining := make([]IncludeOverlap, 0, len(overlaps))
+	allowed := make([]IncludeOverlap, 0)
+	for _, overlap := range overlaps {
+		shared := overlapSharedKey(overlap)
+		isAllowed := false
+		for _, target := range []string{overlap.LeftTarget, overlap.RightTarget} {
+			for _, allowedKey := range allowedKeys[target] {
+				if isSameOrParentPath(allowedKey, shared) {
+					isAllowed = true
+				}
+			}
+		}
+		if isAllowed {
+			allowed = append(allowed, overlap)
+			continue
+		}
+		remaining = append(remaining, overlap)
+	}
+	return remaining, allowed
+}
+
+func overlapSharedKey(overlap IncludeOverlap) string {
+	if isSameOrParentPath(overlap.LeftKey, overlap.RightKey) {
+		return overlap.RightKey
+	}
+	return overlap.LeftKey
+}
+
+// AssignOverlapOwnership gives each overlapping subtree to exactly one
+// profile. With overlap_winner auto (the default) the owner is the platform
+// that natively holds the files: windows for drvfs-backed paths, wsl otherwise.
+func AssignOverlapOwnership(overlaps []IncludeOverlap, config AppConfig) ([]OverlapAssignment, error) {
+	translator := pathTranslatorLoader()
+	assignments := make([]OverlapAssignment, 0, len(overlaps))
+	seen := map[string]struct{}{}
+	for _, overlap := range overlaps {
+		sharedTarget, sharedRaw := overlap.LeftTarget, overlap.LeftPath
+		if isSameOrParentPath(overlap.LeftKey, overlap.RightKey) {
+			sharedTarget, sharedRaw = overlap.RightTarget, overlap.RightPath
+		}
+		shared, ok := translator.ToWSL(ExpandPathVariables(sharedRaw))
+		if !ok {
+			return nil, fmt.Errorf("cannot translate overlapping path: %s", sharedRaw)
+		}
+
+		owner := config.OverlapWinner
+		if owner != overlap.LeftTarget && owner != overlap.RightTarget {
+			owner = "wsl"
+			if shared.WindowsBacked {
+				owner = "windows"
+			}
+		}
+		loser, loserPath := overlap.LeftTarget, overlap.LeftPath
+		if loser == owner {
+			loser, loserPath = overlap.RightTarget, overlap.RightPath
+		}
+
+		assignment := OverlapAssignment{Owner: owner, SharedPath: shared.Path, Loser: loser}
+		if sharedTarget == loser || overlap.LeftKey == overlap.RightKey {
+			assignment.DroppedInclude = loserPath
+		} else {
+			exclude := shared.Path
+			if loser == "windows" {
+				exclude, ok = translator.ToWindows(shared.Path)
+				if !ok {
+					return nil, fmt.Errorf("cannot translate overlapping path for windows: %s", shared.Path)
+				}
+			}
+			assignment.Exclude = exclude
+		}
+
+		key := assignment.Loser + "|" + assignment.Exclude + "|" + assignment.DroppedInclude
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+func PlanOverlapOwnership(plan RunPlan, config AppConfig) ([]OverlapAssignment, error) {
+	overlaps, _ := FilterAllowedOverlaps(FindPlatformIncludeOverlaps(plan, config), config)
+	return AssignOverlapOwnership(overlaps, config)
+}
+
+func formatOverlapAssignment(assignment OverlapAssignment) string {
+	if assignment.DroppedInclude != "" {
+		return fmt.Sprintf("dedupe: %s owned by %s; %s include %s dropped", assignment.SharedPath, assignment.Owner, assignment.Loser, assignment.DroppedInclude)
+	}
+	return fmt.Sprintf("dedupe: %s owned by %s; %s excludes %s", assignment.SharedPath, assignment.Owner, assignment.Loser, assignment.Exclude)
+}
+
+func applyOverlapPolicy(policy string, plan RunPlan, config AppConfig) (RunPlan, []string, error) {
+	if policy == OverlapPolicyOff {
+		return plan, nil, nil
+	}
+	uncheckable := formatUncheckableIncludeWarnings(FindUncheckableIncludes(plan, config))
+	if policy == OverlapPolicyDedupe {
+		assignments, err := PlanOverlapOwnership(plan, config)
+		if err != nil {
+			return RunPlan{}, nil, err
+		}
+		plan.Ownership = assignments
+		// A profile whose every include went to the other one has nothing
+		// left to back up; skip it rather than fail the whole run.
+		warnings := uncheckable
+		targets := make([]string, 0, len(plan.Targets))
+		for _, target := range plan.Targets {
+			if owner := plan.fullyDroppedBy(target, config); owner != "" {
+				warnings = append(warnings, fmt.Sprintf("dedupe: skipped %s %s backup; every include is owned by %s", target, plan.Cadence, owner))
+				continue
+			}
+			targets = append(targets, target)
+		}
+		plan.Targets = targets
+		return plan, warnings, nil
+	}
+
+	overlaps, _ := FilterAllowedOverlaps(FindPlatformIncludeOverlaps(plan, config), config)
+	warnings := formatIncludeOverlapWarnings(overlaps)
+	if len(warnings) == 0 {
+		return plan, uncheckable, nil
+	}
+	if policy == OverlapPolicyStrict {
+		return RunPlan{}, nil, fmt.Errorf("platform include overlap detected in strict mode\n%s", joinLines(warnings))
+	}
+	return plan, append(uncheckable, warnings...), nil
+}
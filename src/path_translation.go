@@ -0,0 +1,385 @@
+package backup
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultAutomountRoot = "/mnt/"
+
+var windowsEnvReference = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// ExpandPathVariables expands $VAR, ${VAR} and Windows-style %VAR% from the
+// environment, as the shell or restic.exe would before reading a configured
+// path. Unset variables are left in place so the path stays recognisably
+// unresolved instead of collapsing to a different absolute path.
+func ExpandPathVariables(rawPath string) string {
+	expanded := windowsEnvReference.ReplaceAllStringFunc(strings.TrimSpace(rawPath), func(reference string) string {
+		if value, ok := os.LookupEnv(strings.Trim(reference, "%")); ok {
+			return value
+		}
+		return reference
+	})
+	return os.Expand(expanded, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return "${" + name + "}"
+	})
+}
+
+type MountEntry struct {
+	Source     string
+	MountPoint string
+	FSType     string
+	Options    string
+}
+
+// WindowsRoot returns the Windows path a drvfs-style mount exposes, such as
+// `C:\` or `\\server\share`, or "" for Linux-native mounts.
+func (entry MountEntry) WindowsRoot() string {
+	switch entry.FSType {
+	case "drvfs":
+		return entry.Source
+	case "9p":
+		for _, option := range strings.Split(entry.Options, ";") {
+			for _, field := range strings.Split(option, ",") {
+				if strings.HasPrefix(field, "path=") && strings.Contains(entry.Options, "aname=drvfs") {
+					return strings.TrimPrefix(field, "path=")
+				}
+			}
+		}
+	}
+	return ""
+}
+
+type TranslatedPath struct {
+	Path          string
+	Key           string
+	WindowsBacked bool
+}
+
+type PathTranslator struct {
+	AutomountRoot   string
+	DistroName      string
+	Mounts          []MountEntry
+	ResolveSymlinks func(string) (string, error)
+}
+
+var pathTranslatorLoader = LoadPathTranslator
+
+func SetPathTranslatorForTests(loader func() PathTranslator) {
+	if loader == nil {
+		pathTranslatorLoader = LoadPathTranslator
+		return
+	}
+	pathTranslatorLoader = loader
+}
+
+func LoadPathTranslator() PathTranslator {
+	wslConf, _ := os.ReadFile("/etc/wsl.conf")
+	procMounts, _ := os.ReadFile("/proc/mounts")
+	translator := NewPathTranslator(string(wslConf), string(procMounts), os.Getenv("WSL_DISTRO_NAME"))
+	translator.ResolveSymlinks = filepath.EvalSymlinks
+	return translator
+}
+
+func NewPathTranslator(wslConf string, procMounts string, distroName string) PathTranslator {
+	return PathTranslator{
+		AutomountRoot: ParseWSLConfAutomountRoot(wslConf),
+		DistroName:    distroName,
+		Mounts:        ParseProcMounts(procMounts),
+	}
+}
+
+func ParseWSLConfAutomountRoot(content string) string {
+	section := ""
+	root := ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if section != "automount" {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.ToLower(strings.TrimSpace(key)) != "root" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if commentIndex := strings.IndexAny(value, "#;"); commentIndex >= 0 {
+			value = strings.TrimSpace(value[:commentIndex])
+		}
+		root = strings.Trim(value, `"'`)
+	}
+
+	if root == "" || !strings.HasPrefix(root, "/") {
+		return defaultAutomountRoot
+	}
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root
+}
+
+func ParseProcMounts(content string) []MountEntry {
+	entries := make([]MountEntry, 0)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		entry := MountEntry{
+			Source:     unescapeMountField(fields[0]),
+			MountPoint: unescapeMountField(fields[1]),
+			FSType:     fields[2],
+		}
+		if len(fields) > 3 {
+			entry.Options = unescapeMountField(fields[3])
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func unescapeMountField(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var builder strings.Builder
+	for index := 0; index < len(value); index++ {
+		if value[index] == '\\' && index+4 <= len(value) {
+			if code, err := strconv.ParseUint(value[index+1:index+4], 8, 8); err == nil {
+				builder.WriteByte(byte(code))
+				index += 3
+				continue
+			}
+		}
+		builder.WriteByte(value[index])
+	}
+	return builder.String()
+}
+
+// ToWSL maps any supported path form to the path WSL sees. Key is the
+// comparison form: Windows-backed segments are lowercased because NTFS is
+// case-insensitive, Linux segments keep their case.
+func (translator PathTranslator) ToWSL(rawPath string) (TranslatedPath, bool) {
+	trimmed := strings.TrimSpace(rawPath)
+	if trimmed == "" {
+		return TranslatedPath{}, false
+	}
+	slashed := strings.ReplaceAll(trimmed, `\`, "/")
+
+	if strings.HasPrefix(slashed, "//") {
+		return translator.translateUNC(slashed)
+	}
+	if isDrivePath(slashed) {
+		return translator.translateWindowsPath(slashed)
+	}
+	if !strings.HasPrefix(slashed, "/") {
+		return TranslatedPath{}, false
+	}
+
+	linuxPath := path.Clean(trimmed)
+	if translator.ResolveSymlinks != nil {
+		if resolved, err := translator.ResolveSymlinks(linuxPath); err == nil && strings.HasPrefix(resolved, "/") {
+			linuxPath = path.Clean(resolved)
+		}
+	}
+	return translator.classifyLinuxPath(linuxPath), true
+}
+
+func (translator PathTranslator) translateUNC(slashed string) (TranslatedPath, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(slashed, "//"), "/", 3)
+	host := strings.ToLower(parts[0])
+	if (host == "wsl$" || host == "wsl.localhost") && len(parts) >= 2 {
+		distro := parts[1]
+		rest := "/"
+		if len(parts) == 3 {
+			rest = path.Clean("/" + parts[2])
+		}
+		if translator.DistroName != "" && strings.EqualFold(distro, translator.DistroName) {
+			return translator.classifyLinuxPath(rest), true
+		}
+		distroRoot := "//wsl/" + distro
+		if rest == "/" {
+			return TranslatedPath{Path: distroRoot, Key: strings.ToLower(distroRoot), WindowsBacked: false}, true
+		}
+		return TranslatedPath{Path: distroRoot + rest, Key: strings.ToLower(distroRoot) + rest, WindowsBacked: false}, true
+	}
+
+	if mounted, ok := translator.mountForWindowsPath(slashed); ok {
+		return mounted, true
+	}
+	cleaned := "/" + path.Clean(slashed)
+	return TranslatedPath{Path: cleaned, Key: strings.ToLower(cleaned), WindowsBacked: true}, true
+}
+
+func (translator PathTranslator) translateWindowsPath(slashed string) (TranslatedPath, bool) {
+	if mounted, ok := translator.mountForWindowsPath(slashed); ok {
+		return mounted, true
+	}
+	root := strings.TrimSuffix(translator.automountRoot(), "/") + "/" + strings.ToLower(slashed[:1])
+	rest := path.Clean("/" + slashed[2:])
+	if rest == "/" {
+		return TranslatedPath{Path: root, Key: root, WindowsBacked: true}, true
+	}
+	return TranslatedPath{Path: root + rest, Key: root + strings.ToLower(rest), WindowsBacked: true}, true
+}
+
+func (translator PathTranslator) mountForWindowsPath(slashed string) (TranslatedPath, bool) {
+	lowered := strings.ToLower(strings.TrimSuffix(slashed, "/"))
+	bestRoot := ""
+	var bestEntry MountEntry
+	for _, entry := range translator.Mounts {
+		root := strings.ToLower(strings.TrimSuffix(strings.ReplaceAll(entry.WindowsRoot(), `\`, "/"), "/"))
+		if root == "" {
+			continue
+		}
+		if lowered != root && !strings.HasPrefix(lowered, root+"/") {
+			continue
+		}
+		if len(root) > len(bestRoot) {
+			bestRoot = root
+			bestEntry = entry
+		}
+	}
+	if bestRoot == "" {
+		return TranslatedPath{}, false
+	}
+
+	mountPoint := path.Clean(bestEntry.MountPoint)
+	rest := strings.TrimSuffix(slashed, "/")[len(bestRoot):]
+	if rest == "" {
+		return TranslatedPath{Path: mountPoint, Key: mountPoint, WindowsBacked: true}, true
+	}
+	rest = path.Clean("/" + rest)
+	return TranslatedPath{Path: joinMountPath(mountPoint, rest), Key: joinMountPath(mountPoint, strings.ToLower(rest)), WindowsBacked: true}, true
+}
+
+func (translator PathTranslator) classifyLinuxPath(linuxPath string) TranslatedPath {
+	if mountPoint, ok := translator.windowsMountPointFor(linuxPath); ok {
+		rest := strings.TrimPrefix(linuxPath, mountPoint)
+		return TranslatedPath{Path: linuxPath, Key: mountPoint + strings.ToLower(rest), WindowsBacked: true}
+	}
+	return TranslatedPath{Path: linuxPath, Key: linuxPath, WindowsBacked: false}
+}
+
+func (translator PathTranslator) windowsMountPointFor(linuxPath string) (string, bool) {
+	best := ""
+	for _, entry := range translator.Mounts {
+		if entry.WindowsRoot() == "" {
+			continue
+		}
+		mountPoint := path.Clean(entry.MountPoint)
+		if isSameOrParentPath(mountPoint, linuxPath) && len(mountPoint) > len(best) {
+			best = mountPoint
+		}
+	}
+	if best != "" {
+		return best, true
+	}
+
+	root := translator.automountRoot()
+	if strings.HasPrefix(linuxPath, root) {
+		rest := strings.TrimPrefix(linuxPath, root)
+		drive, _, _ := strings.Cut(rest, "/")
+		if len(drive) == 1 && isASCIILetter(drive[0]) {
+			return root + drive, true
+		}
+	}
+	return "", false
+}
+
+// ToWindows maps a WSL path to the form Windows programs understand: a drive
+// path for drvfs mounts, otherwise a \\wsl.localhost UNC path.
+func (translator PathTranslator) ToWindows(rawPath string) (string, bool) {
+	slashed := strings.ReplaceAll(strings.TrimSpace(rawPath), `\`, "/")
+	if isDrivePath(slashed) || strings.HasPrefix(slashed, "//") {
+		return strings.ReplaceAll(slashed, "/", `\`), true
+	}
+	if !strings.HasPrefix(slashed, "/") {
+		return "", false
+	}
+	linuxPath := path.Clean(slashed)
+
+	bestMount := ""
+	bestRoot := ""
+	for _, entry := range translator.Mounts {
+		windowsRoot := entry.WindowsRoot()
+		if windowsRoot == "" {
+			continue
+		}
+		mountPoint := path.Clean(entry.MountPoint)
+		if isSameOrParentPath(mountPoint, linuxPath) && len(mountPoint) > len(bestMount) {
+			bestMount = mountPoint
+			bestRoot = windowsRoot
+		}
+	}
+	if bestMount != "" {
+		return joinWindowsPath(bestRoot, strings.TrimPrefix(linuxPath, bestMount)), true
+	}
+
+	root := translator.automountRoot()
+	if strings.HasPrefix(linuxPath+"/", root) {
+		rest := strings.TrimPrefix(linuxPath, root)
+		drive, remainder, _ := strings.Cut(rest, "/")
+		if len(drive) == 1 && isASCIILetter(drive[0]) {
+			return joinWindowsPath(strings.ToUpper(drive)+`:\`, remainder), true
+		}
+	}
+
+	if translator.DistroName == "" {
+		return "", false
+	}
+	return `\\wsl.localhost\` + translator.DistroName + strings.ReplaceAll(linuxPath, "/", `\`), true
+}
+
+func (translator PathTranslator) automountRoot() string {
+	if translator.AutomountRoot == "" {
+		return defaultAutomountRoot
+	}
+	return translator.AutomountRoot
+}
+
+func TranslatedPathsOverlap(left TranslatedPath, right TranslatedPath) bool {
+	return pathsOverlap(left.Key, right.Key)
+}
+
+func joinMountPath(mountPoint string, rest string) string {
+	if mountPoint == "/" {
+		return rest
+	}
+	return mountPoint + rest
+}
+
+func joinWindowsPath(root string, rest string) string {
+	rest = strings.Trim(strings.ReplaceAll(rest, "/", `\`), `\`)
+	if rest == "" {
+		return root
+	}
+	if strings.HasSuffix(root, `\`) {
+		return root + rest
+	}
+	return root + `\` + rest
+}
+
+func isDrivePath(slashed string) bool {
+	return len(slashed) >= 2 && isASCIILetter(slashed[0]) && slashed[1] == ':' && (len(slashed) == 2 || slashed[2] == '/')
+}
+
+func isASCIILetter(value byte) bool {
+	return (value >= 'a' && value <= 'z') || (value >= 'A' && value <= 'Z')
+}
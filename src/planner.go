@@ -3,7 +3,6 @@ package backup
 import (
 	"fmt"
 	"os"
-	"path"
 	"strings"
 )
 
@@ -16,13 +15,52 @@ const (
 )
 
 type RunPlan struct {
-	Cadence string
-	Targets []string
+	Cadence   string
+	Targets   []string
+	Ownership []OverlapAssignment
+}
+
+func (plan RunPlan) ownershipRules(target string) (map[string]bool, []string) {
+	dropped := map[string]bool{}
+	excludes := make([]string, 0)
+	for _, assignment := range plan.Ownership {
+		if assignment.Loser != target {
+			continue
+		}
+		if assignment.DroppedInclude != "" {
+			dropped[assignment.DroppedInclude] = true
+			continue
+		}
+		excludes = append(excludes, assignment.Exclude)
+	}
+	return dropped, excludes
+}
+
+// fullyDroppedBy returns the owner when dedupe dropped every include of
+// target, or "" while target still has something to back up.
+func (plan RunPlan) fullyDroppedBy(target string, config AppConfig) string {
+	dropped, _ := plan.ownershipRules(target)
+	if len(dropped) == 0 {
+		return ""
+	}
+	for _, includePath := range config.Profiles[target].IncludeByCadence.ForCadence(plan.Cadence) {
+		if !dropped[includePath] {
+			return ""
+		}
+	}
+	for _, assignment := range plan.Ownership {
+		if assignment.Loser == target && assignment.DroppedInclude != "" {
+			return assignment.Owner
+		}
+	}
+	return ""
 }
 
 type RestorePlan struct {
 	Target        string
 	RestoreTarget string
+	Snapshot      string
+	Include       string
 }
 
 func DetectRuntime() Runtime {
@@ -73,7 +111,16 @@ func BuildRestorePlan(runtime Runtime, restoreTarget string) (RestorePlan, error
 	}
 }
 
-func FindPlatformIncludeOverlapWarnings(plan RunPlan, config AppConfig) []string {
+type IncludeOverlap struct {
+	LeftTarget  string
+	LeftPath    string
+	LeftKey     string
+	RightTarget string
+	RightPath   string
+	RightKey    string
+}
+
+func FindPlatformIncludeOverlaps(plan RunPlan, config AppConfig) []IncludeOverlap {
 	if len(plan.Targets) < 2 {
 		return nil
 	}
@@ -84,6 +131,7 @@ func FindPlatformIncludeOverlapWarnings(plan RunPlan, config AppConfig) []string
 		normalized string
 	}
 
+	translator := pathTranslatorLoader()
 	items := make([]includeItem, 0)
 	for _, target := range plan.Targets {
 		profile, ok := config.Profiles[target]
@@ -91,15 +139,15 @@ func FindPlatformIncludeOverlapWarnings(plan RunPlan, config AppConfig) []string
 			continue
 		}
 		for _, includePath := range profile.IncludeByCadence.ForCadence(plan.Cadence) {
-			normalized := normalizePlatformPathForOverlap(includePath)
-			if normalized == "" {
+			translated, ok := translator.ToWSL(ExpandPathVariables(includePath))
+			if !ok {
 				continue
 			}
-			items = append(items, includeItem{target: target, rawPath: includePath, normalized: normalized})
+			items = append(items, includeItem{target: target, rawPath: includePath, normalized: translated.Key})
 		}
 	}
 
-	warnings := make([]string, 0)
+	overlaps := make([]IncludeOverlap, 0)
 	seen := map[string]struct{}{}
 	pairKey := func(left includeItem, right includeItem) string {
 		first := left.target + "|" + left.normalized + "|" + left.rawPath
@@ -127,40 +175,74 @@ func FindPlatformIncludeOverlapWarnings(plan RunPlan, config AppConfig) []string
 			}
 			seen[key] = struct{}{}
 
-			warnings = append(warnings,
-				fmt.Sprintf("warning: platform include overlap detected: %s=%s overlaps %s=%s", left.target, left.rawPath, right.target, right.rawPath),
-			)
+			overlaps = append(overlaps, IncludeOverlap{
+				LeftTarget:  left.target,
+				LeftPath:    left.rawPath,
+				LeftKey:     left.normalized,
+				RightTarget: right.target,
+				RightPath:   right.rawPath,
+				RightKey:    right.normalized,
+			})
 		}
 	}
 
-	if len(warnings) > 0 {
-		warnings = append(warnings,
-			"warning: path translation tip: use 'wslpath <path>' and 'wslpath -w <path>' to compare equivalents.",
-		)
+	return overlaps
+}
+
+type UncheckableInclude struct {
+	Target string
+	Path   string
+}
+
+// FindUncheckableIncludes lists the includes the overlap check has to skip
+// because they are not absolute paths even after expanding variables.
+func FindUncheckableIncludes(plan RunPlan, config AppConfig) []UncheckableInclude {
+	uncheckable := make([]UncheckableInclude, 0)
+	if len(plan.Targets) < 2 {
+		return uncheckable
 	}
+	translator := pathTranslatorLoader()
+	for _, target := range plan.Targets {
+		profile, ok := config.Profiles[target]
+		if !ok {
+			continue
+		}
+		for _, includePath := range profile.IncludeByCadence.ForCadence(plan.Cadence) {
+			if _, ok := translator.ToWSL(ExpandPathVariables(includePath)); !ok {
+				uncheckable = append(uncheckable, UncheckableInclude{Target: target, Path: includePath})
+			}
+		}
+	}
+	return uncheckable
+}
 
+func formatUncheckableIncludeWarnings(includes []UncheckableInclude) []string {
+	warnings := make([]string, 0, len(includes))
+	for _, include := range includes {
+		warnings = append(warnings, fmt.Sprintf("warning: uncheckable include: %s=%s is not an absolute path after expanding variables; overlap check skipped", include.Target, include.Path))
+	}
 	return warnings
 }
 
-func normalizePlatformPathForOverlap(rawPath string) string {
-	trimmed := strings.TrimSpace(rawPath)
-	if trimmed == "" {
-		return ""
-	}
+func FindPlatformIncludeOverlapWarnings(plan RunPlan, config AppConfig) []string {
+	return formatIncludeOverlapWarnings(FindPlatformIncludeOverlaps(plan, config))
+}
 
-	normalizedSlashes := strings.ReplaceAll(trimmed, "\\", "/")
-	if len(normalizedSlashes) >= 3 && normalizedSlashes[1] == ':' && normalizedSlashes[2] == '/' {
-		drive := strings.ToLower(normalizedSlashes[:1])
-		rest := normalizedSlashes[2:]
-		normalizedSlashes = "/mnt/" + drive + rest
+func formatIncludeOverlapWarnings(overlaps []IncludeOverlap) []string {
+	warnings := make([]string, 0, len(overlaps)+1)
+	for _, overlap := range overlaps {
+		warnings = append(warnings,
+			fmt.Sprintf("warning: platform include overlap detected: %s=%s overlaps %s=%s", overlap.LeftTarget, overlap.LeftPath, overlap.RightTarget, overlap.RightPath),
+		)
 	}
 
-	normalizedSlashes = strings.ToLower(normalizedSlashes)
-	cleaned := path.Clean(normalizedSlashes)
-	if cleaned == "." {
-		return ""
+	if len(warnings) > 0 {
+		warnings = append(warnings,
+			"warning: path translation tip: use 'wslpath <path>' and 'wslpath -w <path>' to compare equivalents.",
+		)
 	}
-	return cleaned
+
+	return warnings
 }
 
 func pathsOverlap(left string, right string) bool {
@@ -0,0 +1,62 @@
+package backup
+
+import "sort"
+
+var excludePresets = map[string][]string{
+	"dev-caches": {
+		"**/node_modules",
+		"**/.npm/_cacache",
+		"**/.pnpm-store",
+		"**/.yarn/cache",
+		"**/__pycache__",
+		"**/.pytest_cache",
+		"**/.mypy_cache",
+		"**/.cache/go-build",
+		"**/go/pkg/mod/cache",
+		"**/.cargo/registry",
+		"**/.cargo/git",
+		"**/target/debug",
+		"**/target/release",
+		"**/.gradle/caches",
+		"**/.m2/repository",
+	},
+	"windows-temp": {
+		"**/AppData/Local/Temp",
+		"**/AppData/Local/CrashDumps",
+		"**/Windows/Temp",
+	},
+	"browser-caches": {
+		"**/AppData/Local/Google/Chrome/User Data/*/Cache",
+		"**/AppData/Local/Google/Chrome/User Data/*/Code Cache",
+		"**/AppData/Local/Microsoft/Edge/User Data/*/Cache",
+		"**/AppData/Local/Microsoft/Edge/User Data/*/Code Cache",
+		"**/AppData/Local/Mozilla/Firefox/Profiles/*/cache2",
+		"**/.cache/google-chrome",
+		"**/.cache/chromium",
+		"**/.cache/mozilla",
+	},
+	"vm-images": {
+		"**/*.vhdx",
+		"**/*.vhd",
+		"**/*.vmdk",
+		"**/*.vdi",
+		"**/*.qcow2",
+	},
+}
+
+func ExcludePresetNames() []string {
+	names := make([]string, 0, len(excludePresets))
+	for name := range excludePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ExcludePresetPatterns(name string) ([]string, bool) {
+	patterns, ok := excludePresets[name]
+	if !ok {
+		return nil, false
+	}
+	return append([]string{}, patterns...), true
+}
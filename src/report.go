@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func BuildExcludedReport(plan RunPlan, config AppConfig) (string, error) {
+	lines := []string{fmt.Sprintf("%s backup report (excluded):", plan.Cadence)}
+	for _, target := range plan.Targets {
+		profile, ok := config.Profiles[target]
+		if !ok {
+			return "", fmt.Errorf("missing profile config: %s", target)
+		}
+
+		presetByPattern := map[string]string{}
+		for _, presetName := range profile.ExcludePresets {
+			patterns, _ := ExcludePresetPatterns(presetName)
+			for _, pattern := range patterns {
+				if _, exists := presetByPattern[pattern]; !exists {
+					presetByPattern[pattern] = presetName
+				}
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf("%s:", target))
+		if len(profile.ExcludePresets) > 0 {
+			lines = append(lines, fmt.Sprintf("  presets: %s", strings.Join(profile.ExcludePresets, ", ")))
+		}
+
+		excludes := profile.ExcludeByCadence.ForCadence(plan.Cadence)
+		if len(excludes) == 0 {
+			lines = append(lines, "  (none)")
+			continue
+		}
+		for _, exclude := range excludes {
+			if presetName, fromPreset := presetByPattern[exclude]; fromPreset {
+				lines = append(lines, fmt.Sprintf("  - %s (preset: %s)", exclude, presetName))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("  - %s", exclude))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func BuildConfigShowReport(config AppConfig) string {
+	lines := []string{}
+	if !config.Exists {
+		lines = append(lines, fmt.Sprintf("no config file found; using built-in defaults (user config path: %s)", config.Path))
+	} else {
+		lines = append(lines, "config layers (lowest to highest precedence):")
+		for _, layer := range config.Layers {
+			lines = append(lines, fmt.Sprintf("  %s", layer))
+		}
+	}
+
+	sourceSuffix := func(key string) string {
+		if source, ok := config.Sources[key]; ok {
+			return fmt.Sprintf("  (from %s)", source)
+		}
+		return ""
+	}
+
+	lines = append(lines, fmt.Sprintf("restic version: %s", ResticVersionRequirement(config)))
+	lines = append(lines, fmt.Sprintf("overlap policy: %s%s", ResolveOverlapPolicy("", config), sourceSuffix("overlap_policy")))
+	ownershipByCadence := map[string][]OverlapAssignment{}
+	if ResolveOverlapPolicy("", config) == OverlapPolicyDedupe {
+		winner := config.OverlapWinner
+		if winner == "" {
+			winner = OverlapWinnerAuto
+		}
+		lines = append(lines, fmt.Sprintf("overlap winner: %s%s", winner, sourceSuffix("overlap_winner")))
+		for _, cadence := range cadences {
+			plan, err := BuildRunPlan(cadence, RuntimeWSL)
+			if err != nil {
+				continue
+			}
+			assignments, err := PlanOverlapOwnership(plan, config)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("overlap dedupe error (%s): %v", cadence, err))
+				continue
+			}
+			ownershipByCadence[cadence] = assignments
+		}
+	}
+	lines = append(lines, "profiles:")
+	for _, profileName := range sortedProfileNames(config) {
+		profile := config.Profiles[profileName]
+		lines = append(lines, fmt.Sprintf("  %s:", profileName))
+		lines = append(lines, fmt.Sprintf("    repository: %s%s", profile.RepositoryHint, sourceSuffix(profileSourceKey(profileName, "repository"))))
+		lines = append(lines, fmt.Sprintf("    restic: %s%s", resticExecutable(profileName, profile), sourceSuffix(profileSourceKey(profileName, "restic_path"))))
+		lines = append(lines, fmt.Sprintf("    use_fs_snapshot: %t%s", profile.UseFSSnapshot, sourceSuffix(profileSourceKey(profileName, "use_fs_snapshot"))))
+		if profile.VSSElevation != "" {
+			lines = append(lines, fmt.Sprintf("    vss_elevation: %s%s", profile.VSSElevation, sourceSuffix(profileSourceKey(profileName, "vss_elevation"))))
+		}
+		host := SnapshotHost(profile)
+		if host == "" {
+			host = "(restic default)"
+		}
+		lines = append(lines, fmt.Sprintf("    host: %s%s", host, sourceSuffix(profileSourceKey(profileName, "host"))))
+		if len(profile.Tags) > 0 {
+			lines = append(lines, fmt.Sprintf("    tags: %s%s", strings.Join(profile.Tags, ", "), sourceSuffix(profileSourceKey(profileName, "tags"))))
+		}
+		if optionArgs := profile.BackupOptions.Args(); len(optionArgs) > 0 {
+			lines = append(lines, fmt.Sprintf("    backup_options: %s%s", strings.Join(optionArgs, " "), sourceSuffix(profileSourceKey(profileName, "backup_options"))))
+		}
+		if len(profile.ExcludePresets) > 0 {
+			lines = append(lines, fmt.Sprintf("    exclude_presets: %s%s", strings.Join(profile.ExcludePresets, ", "), sourceSuffix(profileSourceKey(profileName, "exclude_presets"))))
+		}
+		if len(profile.AllowedOverlaps) > 0 {
+			lines = append(lines, fmt.Sprintf("    allowed_overlaps: %s%s", strings.Join(profile.AllowedOverlaps, ", "), sourceSuffix(profileSourceKey(profileName, "allowed_overlaps"))))
+		}
+		for _, cadence := range cadences {
+			for _, kind := range []string{RuleKindInclude, RuleKindExclude} {
+				entries := configShowRuleEntries(profile, kind, cadence)
+				if len(entries) == 0 {
+					lines = append(lines, fmt.Sprintf("    %s %s: (none)", cadence, kind))
+					continue
+				}
+				lines = append(lines, fmt.Sprintf("    %s %s:", cadence, kind))
+				for _, entry := range entries {
+					lines = append(lines, fmt.Sprintf("      - %s  (from %s)", entry.Path, describeRuleOrigin(entry)))
+				}
+			}
+			for _, stage := range []string{HookPre, HookPost, HookOnFailure} {
+				if commands := profile.Hooks.commands(stage, cadence); len(commands) > 0 {
+					lines = append(lines, fmt.Sprintf("    %s %s hooks: %s%s", cadence, stage, strings.Join(commands, "; "), sourceSuffix(profileSourceKey(profileName, "hooks"))))
+				}
+			}
+			for _, assignment := range ownershipByCadence[cadence] {
+				if assignment.Loser != profileName {
+					continue
+				}
+				if assignment.DroppedInclude != "" {
+					lines = append(lines, fmt.Sprintf("    %s dropped include: %s  (dedupe: owned by %s)", cadence, assignment.DroppedInclude, assignment.Owner))
+					continue
+				}
+				lines = append(lines, fmt.Sprintf("    %s generated exclude: %s  (dedupe: owned by %s)", cadence, assignment.Exclude, assignment.Owner))
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func sortedProfileNames(config AppConfig) []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configShowRuleEntries returns a cadence's rules in evaluation order. The
+// built-in default profiles carry no rule entries, only their paths.
+func configShowRuleEntries(profile ProfileConfig, kind string, cadence string) []RuleEntry {
+	entries := make([]RuleEntry, 0)
+	if profile.Rules == nil {
+		paths := profile.IncludeByCadence
+		if kind == RuleKindExclude {
+			paths = profile.ExcludeByCadence
+		}
+		for _, value := range paths.ForCadence(cadence) {
+			entries = append(entries, RuleEntry{Kind: kind, Cadence: cadence, Path: value, Origin: "built-in defaults"})
+		}
+		return entries
+	}
+	for _, rule := range profile.Rules {
+		if rule.Kind == kind && rule.Cadence == cadence {
+			entries = append(entries, rule)
+		}
+	}
+	return entries
+}
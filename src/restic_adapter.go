@@ -6,6 +6,12 @@ type ResticInvocation struct {
 	Target     string
 	Executable string
 	Args       []string
+
+	// Kept apart from Args so long include and exclude lists can be moved
+	// into list files before execution.
+	baseArgs     []string
+	includePaths []string
+	excludePaths []string
 }
 
 func BuildResticInvocations(plan RunPlan, config AppConfig) ([]ResticInvocation, error) {
@@ -16,8 +22,17 @@ func BuildResticInvocations(plan RunPlan, config AppConfig) ([]ResticInvocation,
 		if !ok {
 			return nil, fmt.Errorf("missing profile config: %s", target)
 		}
-		includePaths := profile.IncludeByCadence.ForCadence(plan.Cadence)
-		excludePaths := profile.ExcludeByCadence.ForCadence(plan.Cadence)
+		if plan.fullyDroppedBy(target, config) != "" {
+			continue
+		}
+		droppedIncludes, generatedExcludes := plan.ownershipRules(target)
+		includePaths := make([]string, 0)
+		for _, includePath := range profile.IncludeByCadence.ForCadence(plan.Cadence) {
+			if !droppedIncludes[includePath] {
+				includePaths = append(includePaths, includePath)
+			}
+		}
+		excludePaths := append(append([]string{}, profile.ExcludeByCadence.ForCadence(plan.Cadence)...), generatedExcludes...)
 
 		if len(includePaths) == 0 {
 			return nil, fmt.Errorf("missing include paths for target: %s", target)
@@ -26,24 +41,32 @@ func BuildResticInvocations(plan RunPlan, config AppConfig) ([]ResticInvocation,
 			return nil, fmt.Errorf("missing repository for target: %s", target)
 		}
 
-		args := []string{"-r", profile.RepositoryHint, "backup"}
+		// --json makes restic end with a summary line that run history and
+		// metrics read bytes added and files changed from.
+		args := []string{"-r", profile.RepositoryHint, "backup", "--json"}
+		if host := SnapshotHost(profile); host != "" {
+			args = append(args, "--host", host)
+		}
+		for _, tag := range SnapshotTags(plan.Cadence, target, profile) {
+			args = append(args, "--tag", tag)
+		}
 		if profile.UseFSSnapshot {
 			args = append(args, "--use-fs-snapshot")
 		}
+		args = append(args, profile.BackupOptions.Args()...)
+		baseArgs := append([]string{}, args...)
 		for _, excludePath := range excludePaths {
 			args = append(args, "--exclude", excludePath)
 		}
 		args = append(args, includePaths...)
 
-		executable := "restic"
-		if target == "windows" {
-			executable = "restic.exe"
-		}
-
 		invocations = append(invocations, ResticInvocation{
-			Target:     target,
-			Executable: executable,
-			Args:       args,
+			Target:       target,
+			Executable:   resticExecutable(target, profile),
+			Args:         args,
+			baseArgs:     baseArgs,
+			includePaths: includePaths,
+			excludePaths: excludePaths,
 		})
 	}
 
@@ -59,11 +82,23 @@ func BuildRestoreInvocation(plan RestorePlan, config AppConfig) (ResticInvocatio
 		return ResticInvocation{}, fmt.Errorf("missing repository for target: %s", plan.Target)
 	}
 
-	args := []string{"-r", profile.RepositoryHint, "restore", "latest", "--target", plan.RestoreTarget}
-
-	executable := "restic"
+	executable, repository := resticExecutable(plan.Target, profile), profile.RepositoryHint
+	// Windows snapshots are restored by the WSL-side binary so a Linux
+	// restore target needs no translation.
 	if plan.Target == "windows" {
-		executable = "restic.exe"
+		var err error
+		executable, repository, err = browseRepository(plan.Target, config)
+		if err != nil {
+			return ResticInvocation{}, err
+		}
+	}
+	snapshot := plan.Snapshot
+	if snapshot == "" {
+		snapshot = "latest"
+	}
+	args := []string{"-r", repository, "restore", snapshot, "--target", plan.RestoreTarget}
+	if plan.Include != "" {
+		args = append(args, "--include", plan.Include)
 	}
 
 	return ResticInvocation{
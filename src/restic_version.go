@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PinnedResticVersion mirrors scripts/restic-version.yaml; update both together.
+const PinnedResticVersion = "0.17.3"
+
+type ResticVersionCheck struct {
+	Target     string
+	Executable string
+	Found      string
+	Err        error
+}
+
+func resticExecutable(target string, profile ProfileConfig) string {
+	if strings.TrimSpace(profile.ResticPath) != "" {
+		return profile.ResticPath
+	}
+	if target == "windows" {
+		return "restic.exe"
+	}
+	return "restic"
+}
+
+func ResticVersionRequirement(config AppConfig) string {
+	if config.ResticMinVersion != "" {
+		return ">= " + config.ResticMinVersion
+	}
+	if config.ResticVersion != "" {
+		return config.ResticVersion
+	}
+	return PinnedResticVersion
+}
+
+func CheckResticVersion(target string, executable string, config AppConfig, executor Executor) ResticVersionCheck {
+	check := ResticVersionCheck{Target: target, Executable: executable}
+
+	output, err := targetExecutor(target, executor).Run(executable, "version")
+	if err != nil {
+		check.Err = fmt.Errorf("run %s version: %w", executable, err)
+		return check
+	}
+
+	found, err := parseResticVersion(output)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Found = found
+
+	if config.ResticMinVersion != "" {
+		if compareVersions(found, config.ResticMinVersion) < 0 {
+			check.Err = fmt.Errorf("restic version too old for %s (%s): found %s, required >= %s", target, executable, found, config.ResticMinVersion)
+		}
+		return check
+	}
+
+	required := ResticVersionRequirement(config)
+	if compareVersions(found, required) != 0 {
+		check.Err = fmt.Errorf("restic version mismatch for %s (%s): found %s, required %s", target, executable, found, required)
+	}
+	return check
+}
+
+func PreflightResticVersions(invocations []ResticInvocation, config AppConfig, executor Executor) error {
+	checked := map[string]struct{}{}
+	for _, invocation := range invocations {
+		key := invocation.Target + "|" + invocation.Executable
+		if _, exists := checked[key]; exists {
+			continue
+		}
+		checked[key] = struct{}{}
+
+		if check := CheckResticVersion(invocation.Target, invocation.Executable, config, executor); check.Err != nil {
+			return fmt.Errorf("restic preflight failed: %w", check.Err)
+		}
+	}
+	return nil
+}
+
+func parseResticVersion(output string) (string, error) {
+	fields := strings.Fields(output)
+	for index, field := range fields {
+		if field == "restic" && index+1 < len(fields) {
+			version := strings.TrimPrefix(fields[index+1], "v")
+			if isVersionString(version) {
+				return version, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unrecognized restic version output: %q", strings.TrimSpace(output))
+}
+
+func isVersionString(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, part := range strings.Split(value, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func compareVersions(left string, right string) int {
+	leftParts := strings.Split(strings.TrimPrefix(left, "v"), ".")
+	rightParts := strings.Split(strings.TrimPrefix(right, "v"), ".")
+	for index := 0; index < len(leftParts) || index < len(rightParts); index++ {
+		leftValue, rightValue := 0, 0
+		if index < len(leftParts) {
+			leftValue, _ = strconv.Atoi(leftParts[index])
+		}
+		if index < len(rightParts) {
+			rightValue, _ = strconv.Atoi(rightParts[index])
+		}
+		if leftValue != rightValue {
+			if leftValue < rightValue {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
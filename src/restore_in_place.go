@@ -0,0 +1,435 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ConflictSkip               = "skip"
+	ConflictOverwriteIfNewer   = "overwrite-if-newer"
+	ConflictRename             = "rename"
+	ConflictAbort              = "abort"
+	defaultRestoreRenameSuffix = ".restored"
+)
+
+var conflictPolicies = []string{ConflictSkip, ConflictOverwriteIfNewer, ConflictRename, ConflictAbort}
+
+const (
+	restoreActionCreate    = "create"
+	restoreActionOverwrite = "overwrite"
+	restoreActionRename    = "rename"
+	restoreActionSkip      = "skip"
+	restoreActionUnchanged = "unchanged"
+)
+
+// RestoreAction is what an in-place restore does with one file of the
+// snapshot. Destination differs from LocalPath only for renamed copies.
+type RestoreAction struct {
+	Action      string
+	TreePath    string
+	LocalPath   string
+	Destination string
+	Snapshot    SnapshotFile
+	Local       SnapshotFile
+}
+
+type InPlaceRestorePlan struct {
+	Profile  string
+	Snapshot Snapshot
+	Actions  []RestoreAction
+}
+
+func (plan InPlaceRestorePlan) count(action string) int {
+	total := 0
+	for _, entry := range plan.Actions {
+		if entry.Action == action {
+			total++
+		}
+	}
+	return total
+}
+
+// BuildInPlaceRestorePlan compares every file of the snapshot (limited to
+// include when set) with its original location and applies the conflict
+// policy to files that exist with a different size or mtime.
+func BuildInPlaceRestorePlan(profileName string, snapshot Snapshot, files map[string]SnapshotFile, include string, policy string, suffix string) (InPlaceRestorePlan, error) {
+	includeTree := ""
+	if include != "" {
+		includeTree = restoreIncludeTreePath(profileName, include)
+	}
+	treePaths := make([]string, 0, len(files))
+	for treePath := range files {
+		if includeTree == "" || treePath == includeTree || strings.HasPrefix(treePath, strings.TrimSuffix(includeTree, "/")+"/") {
+			treePaths = append(treePaths, treePath)
+		}
+	}
+	if len(treePaths) == 0 {
+		if include != "" {
+			return InPlaceRestorePlan{}, fmt.Errorf("snapshot %s has no files under %s", snapshot.ShortID, include)
+		}
+		return InPlaceRestorePlan{}, fmt.Errorf("snapshot %s has no files", snapshot.ShortID)
+	}
+	sort.Strings(treePaths)
+
+	plan := InPlaceRestorePlan{Profile: profileName, Snapshot: snapshot}
+	conflicts := make([]string, 0)
+	claimed := map[string]bool{}
+	for _, treePath := range treePaths {
+		claimed[localSnapshotPath(profileName, treePath)] = true
+	}
+	for _, treePath := range treePaths {
+		localPath := localSnapshotPath(profileName, treePath)
+		entry := RestoreAction{TreePath: treePath, LocalPath: localPath, Destination: localPath, Snapshot: files[treePath]}
+		info, err := os.Stat(localPath)
+		switch {
+		case os.IsNotExist(err):
+			entry.Action = restoreActionCreate
+		case err != nil:
+			return InPlaceRestorePlan{}, fmt.Errorf("inspect %s: %w", localPath, err)
+		case info.IsDir():
+			return InPlaceRestorePlan{}, fmt.Errorf("cannot restore %s: a directory is in the way", localPath)
+		default:
+			entry.Local = SnapshotFile{Size: uint64(info.Size()), ModTime: info.ModTime()}
+			if entry.Local.Size == entry.Snapshot.Size && entry.Local.ModTime.Truncate(time.Second).Equal(entry.Snapshot.ModTime.Truncate(time.Second)) {
+				entry.Action = restoreActionUnchanged
+				break
+			}
+			switch policy {
+			case ConflictSkip:
+				entry.Action = restoreActionSkip
+			case ConflictOverwriteIfNewer:
+				entry.Action = restoreActionSkip
+				if entry.Snapshot.ModTime.After(entry.Local.ModTime) {
+					entry.Action = restoreActionOverwrite
+				}
+			case ConflictRename:
+				entry.Action = restoreActionRename
+				entry.Destination, err = uniqueRestoreDestination(localPath+suffix, claimed)
+				if err != nil {
+					return InPlaceRestorePlan{}, err
+				}
+			case ConflictAbort:
+				conflicts = append(conflicts, localPath)
+			default:
+				return InPlaceRestorePlan{}, fmt.Errorf("invalid conflict policy: %s (expected %s)", policy, strings.Join(conflictPolicies, ", "))
+			}
+		}
+		plan.Actions = append(plan.Actions, entry)
+	}
+	if len(conflicts) > 0 {
+		return InPlaceRestorePlan{}, fmt.Errorf("restore aborted: %d file(s) differ from snapshot %s:\n  %s", len(conflicts), snapshot.ShortID, strings.Join(conflicts, "\n  "))
+	}
+	return plan, nil
+}
+
+// uniqueRestoreDestination picks the first of name, name.1, name.2, ... that
+// neither exists nor is written by another file of the same restore, so a
+// renamed copy never replaces anything.
+func uniqueRestoreDestination(name string, claimed map[string]bool) (string, error) {
+	for attempt := 0; attempt < 1000; attempt++ {
+		candidate := name
+		if attempt > 0 {
+			candidate = fmt.Sprintf("%s.%d", name, attempt)
+		}
+		if claimed[candidate] {
+			continue
+		}
+		if _, err := os.Lstat(candidate); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("inspect %s: %w", candidate, err)
+		}
+		claimed[candidate] = true
+		return candidate, nil
+	}
+	return "", fmt.Errorf("no free name for the restored copy %s", name)
+}
+
+// restoreIncludeTreePath accepts an include in the form the user knows the
+// file by (`C:\...`, `/mnt/c/...` or a Linux path) and returns restic's
+// snapshot path for it.
+func restoreIncludeTreePath(profileName string, include string) string {
+	if profileName == "windows" && strings.HasPrefix(include, "/") && !isResticDriveTreePath(include) {
+		if windowsPath, ok := pathTranslatorLoader().ToWindows(include); ok {
+			return resticTreePath(windowsPath)
+		}
+	}
+	return resticTreePath(include)
+}
+
+func FormatInPlaceRestorePreview(plan InPlaceRestorePlan) []string {
+	lines := []string{fmt.Sprintf("in-place restore of %s snapshot %s:", plan.Profile, describeDiffSnapshot(plan.Snapshot))}
+	for _, entry := range plan.Actions {
+		switch entry.Action {
+		case restoreActionCreate:
+			lines = append(lines, fmt.Sprintf("  create    %s (%s)", entry.LocalPath, formatBytes(entry.Snapshot.Size)))
+		case restoreActionOverwrite:
+			lines = append(lines, fmt.Sprintf("  overwrite %s (snapshot %s, local %s)", entry.LocalPath, formatRestoreTime(entry.Snapshot.ModTime), formatRestoreTime(entry.Local.ModTime)))
+		case restoreActionRename:
+			lines = append(lines, fmt.Sprintf("  rename    %s -> %s", entry.LocalPath, entry.Destination))
+		case restoreActionSkip:
+			lines = append(lines, fmt.Sprintf("  skip      %s (local %s, snapshot %s)", entry.LocalPath, formatRestoreTime(entry.Local.ModTime), formatRestoreTime(entry.Snapshot.ModTime)))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("  %d to create, %d to overwrite, %d to restore beside the local copy, %d skipped, %d unchanged",
+		plan.count(restoreActionCreate), plan.count(restoreActionOverwrite), plan.count(restoreActionRename), plan.count(restoreActionSkip), plan.count(restoreActionUnchanged)))
+	if overwrites := plan.count(restoreActionOverwrite); overwrites > 0 {
+		lines = append(lines, fmt.Sprintf("  the %d file(s) to overwrite are backed up first (tag %s)", overwrites, TagPreRestore))
+	}
+	return lines
+}
+
+func formatRestoreTime(value time.Time) string {
+	return value.Local().Format("2006-01-02 15:04:05")
+}
+
+// RunInPlaceRestore always prints the preview. Without --yes it stops there;
+// with --yes it backs up the files it will overwrite, restores the snapshot
+// into a staging dir and moves each file to its original location.
+func RunInPlaceRestore(command Command, config AppConfig, executor Executor, progress io.Writer) (string, error) {
+	profileName := command.Profile
+	if profileName == "" {
+		profileName = "wsl"
+	}
+	policy := command.Conflict
+	if policy == "" {
+		policy = ConflictSkip
+	}
+	suffix := command.Suffix
+	if suffix == "" {
+		suffix = defaultRestoreRenameSuffix
+	}
+
+	// Hold the lock from planning on, so a backup or another restore cannot
+	// change the files between the preview and the writes.
+	if command.Yes {
+		lock, err := AcquireInstanceLock("restore --in-place", command.Wait)
+		if err != nil {
+			return "", err
+		}
+		defer lock.Release()
+	}
+
+	executable, repository, err := browseRepository(profileName, config)
+	if err != nil {
+		return "", err
+	}
+	if check := CheckResticVersion("wsl", executable, config, executor); check.Err != nil {
+		return "", fmt.Errorf("restic preflight failed: %w", check.Err)
+	}
+	snapshots, err := ListSnapshots(executable, repository, nil, executor)
+	if err != nil {
+		return "", err
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no snapshots in %s repository %s", profileName, repository)
+	}
+	ref := command.Snapshot
+	if ref == "" {
+		ref = "latest"
+	}
+	snapshot, err := resolveSnapshotRef(snapshots, ref)
+	if err != nil {
+		return "", err
+	}
+	files, err := listSnapshotFiles(executable, repository, snapshot, executor)
+	if err != nil {
+		return "", err
+	}
+	plan, err := BuildInPlaceRestorePlan(profileName, snapshot, files, command.Include, policy, suffix)
+	if err != nil {
+		return "", err
+	}
+
+	preview := FormatInPlaceRestorePreview(plan)
+	if !command.Yes {
+		return joinLines(append(preview, "preview only; rerun with --yes to apply")), nil
+	}
+	_, _ = fmt.Fprintln(progress, joinLines(preview))
+	if plan.count(restoreActionCreate)+plan.count(restoreActionOverwrite)+plan.count(restoreActionRename) == 0 {
+		return "nothing to restore.", nil
+	}
+
+	preRestoreID, err := backupFilesBeforeRestore(profileName, plan, config, executor)
+	if err != nil {
+		return "", err
+	}
+	if preRestoreID != "" {
+		_, _ = fmt.Fprintf(progress, "pre-restore snapshot %s\n", preRestoreID)
+	}
+
+	stagingDir, err := createRestoreStagingDir()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	includeFile, err := writeRestoreIncludeFile(stagingDir, plan)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(includeFile) }()
+
+	args := []string{"-r", repository, "restore", snapshot.ID, "--target", stagingDir, "--include-file", includeFile}
+	if _, err := executor.Run(executable, args...); err != nil {
+		return "", fmt.Errorf("restore snapshot %s: %w", snapshot.ShortID, err)
+	}
+
+	restored := 0
+	for _, entry := range plan.Actions {
+		if !isRestoreWrite(entry.Action) {
+			continue
+		}
+		staged := filepath.Join(stagingDir, filepath.FromSlash(entry.TreePath))
+		if entry.Action == restoreActionRename {
+			if _, err := os.Lstat(entry.Destination); err == nil {
+				return "", fmt.Errorf("restored %d file(s) before failing: %s appeared during the restore; not overwriting it", restored, entry.Destination)
+			}
+		}
+		if err := placeRestoredFile(staged, entry.Destination); err != nil {
+			return "", fmt.Errorf("restored %d file(s) before failing: %w", restored, err)
+		}
+		restored++
+	}
+	summary := fmt.Sprintf("restored %d file(s) in place from %s snapshot %s", restored, profileName, snapshot.ShortID)
+	if preRestoreID != "" {
+		summary += fmt.Sprintf("; overwritten files are in snapshot %s", preRestoreID)
+	}
+	return summary + ".", nil
+}
+
+func isRestoreWrite(action string) bool {
+	return action == restoreActionCreate || action == restoreActionOverwrite || action == restoreActionRename
+}
+
+// backupFilesBeforeRestore snapshots the files an overwrite would replace
+// with the profile's own restic, into its own repository. It returns the
+// new snapshot ID, or "" when nothing is overwritten.
+func backupFilesBeforeRestore(profileName string, plan InPlaceRestorePlan, config AppConfig, executor Executor) (string, error) {
+	includePaths := make([]string, 0)
+	for _, entry := range plan.Actions {
+		if entry.Action == restoreActionOverwrite {
+			includePaths = append(includePaths, entry.LocalPath)
+		}
+	}
+	if len(includePaths) == 0 {
+		return "", nil
+	}
+	profile := config.Profiles[profileName]
+	baseArgs := []string{"-r", profile.RepositoryHint, "backup", "--json"}
+	if host := SnapshotHost(profile); host != "" {
+		baseArgs = append(baseArgs, "--host", host)
+	}
+	baseArgs = append(baseArgs, "--tag", TagPreRestore, "--tag", TagPrefixProfile+profileName, "--tag", TagPrefixVersion+Version)
+	invocation := ResticInvocation{
+		Target:       profileName,
+		Executable:   resticExecutable(profileName, profile),
+		Args:         append(append([]string{}, baseArgs...), includePaths...),
+		baseArgs:     baseArgs,
+		includePaths: includePaths,
+	}
+	invocations, cleanup, err := SpillRuleLists([]ResticInvocation{invocation})
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	result := runResticInvocation(invocations[0], executor)
+	if result.Err != nil {
+		return "", fmt.Errorf("pre-restore backup failed, nothing was restored: %w", result.Err)
+	}
+	summary, ok := ParseBackupSummary(result.Output)
+	if !ok || summary.SnapshotID == "" {
+		return "", fmt.Errorf("pre-restore backup reported no snapshot, nothing was restored")
+	}
+	return summary.SnapshotID, nil
+}
+
+// writeRestoreIncludeFile lists the files to restore, one escaped pattern
+// per line, beside the staging dir; one --include per file would overflow
+// the command line for a large restore.
+func writeRestoreIncludeFile(stagingDir string, plan InPlaceRestorePlan) (string, error) {
+	patterns := make([]string, 0, len(plan.Actions))
+	for _, entry := range plan.Actions {
+		if isRestoreWrite(entry.Action) {
+			patterns = append(patterns, escapeRestorePattern(entry.TreePath))
+		}
+	}
+	includeFile := stagingDir + ".include"
+	if err := os.WriteFile(includeFile, []byte(strings.Join(patterns, "\n")+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("write restore include file: %w", err)
+	}
+	return includeFile, nil
+}
+
+func createRestoreStagingDir() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(stateDir, "tmp")
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", fmt.Errorf("create restore staging dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, "restore-")
+	if err != nil {
+		return "", fmt.Errorf("create restore staging dir: %w", err)
+	}
+	return dir, nil
+}
+
+// escapeRestorePattern keeps restic from reading glob characters in a file
+// name as a pattern.
+func escapeRestorePattern(treePath string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	return replacer.Replace(path.Clean(treePath))
+}
+
+// placeRestoredFile copies through a temporary file next to the destination
+// so an interrupted copy never leaves a half-written original behind, and
+// keeps the mode and mtime restic restored.
+func placeRestoredFile(staged string, destination string) error {
+	info, err := os.Stat(staged)
+	if err != nil {
+		return fmt.Errorf("restic did not restore %s: %w", destination, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return fmt.Errorf("create parent of %s: %w", destination, err)
+	}
+	source, err := os.Open(staged)
+	if err != nil {
+		return fmt.Errorf("open restored %s: %w", destination, err)
+	}
+	defer source.Close()
+
+	temporary := destination + ".restore-tmp"
+	target, err := os.OpenFile(temporary, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("write %s: %w", destination, err)
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		_ = target.Close()
+		_ = os.Remove(temporary)
+		return fmt.Errorf("write %s: %w", destination, err)
+	}
+	if err := target.Close(); err != nil {
+		_ = os.Remove(temporary)
+		return fmt.Errorf("write %s: %w", destination, err)
+	}
+	if err := os.Chtimes(temporary, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(temporary)
+		return fmt.Errorf("set mtime of %s: %w", destination, err)
+	}
+	if err := os.Rename(temporary, destination); err != nil {
+		_ = os.Remove(temporary)
+		return fmt.Errorf("replace %s: %w", destination, err)
+	}
+	return nil
+}
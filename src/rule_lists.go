@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Windows caps a command line at 32767 characters, and wsl.exe interop adds
+// its own overhead; stay well below that.
+const ruleListArgvThreshold = 24 * 1024
+
+func commandLineLength(executable string, args []string) int {
+	length := len(executable)
+	for _, arg := range args {
+		length += len(arg) + 3
+	}
+	return length
+}
+
+func needsRuleListFiles(invocation ResticInvocation) bool {
+	return invocation.baseArgs != nil && commandLineLength(invocation.Executable, invocation.Args) > ruleListArgvThreshold
+}
+
+// SpillRuleLists rewrites invocations whose command line would be too long
+// to read their includes from --files-from-verbatim and their excludes from
+// --exclude-file. The lists live under the state dir so restic.exe can reach
+// them through their translated path. The returned cleanup removes them.
+func SpillRuleLists(invocations []ResticInvocation) ([]ResticInvocation, func(), error) {
+	cleanup := func() {}
+	listDir := ""
+	rewritten := make([]ResticInvocation, 0, len(invocations))
+	for _, invocation := range invocations {
+		if !needsRuleListFiles(invocation) {
+			rewritten = append(rewritten, invocation)
+			continue
+		}
+		if listDir == "" {
+			dir, err := createRuleListDir()
+			if err != nil {
+				return nil, cleanup, err
+			}
+			listDir = dir
+			cleanup = func() { _ = os.RemoveAll(dir) }
+		}
+
+		args := append([]string{}, invocation.baseArgs...)
+		if len(invocation.excludePaths) > 0 {
+			excludeFile, err := writeRuleListFile(listDir, invocation.Target, RuleKindExclude, invocation.excludePaths)
+			if err != nil {
+				cleanup()
+				return nil, func() {}, err
+			}
+			args = append(args, "--exclude-file", excludeFile)
+		}
+		includeFile, err := writeRuleListFile(listDir, invocation.Target, RuleKindInclude, invocation.includePaths)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		invocation.Args = append(args, "--files-from-verbatim", includeFile)
+		rewritten = append(rewritten, invocation)
+	}
+	return rewritten, cleanup, nil
+}
+
+func createRuleListDir() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(stateDir, "tmp")
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", fmt.Errorf("create rule list dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, "rule-lists-")
+	if err != nil {
+		return "", fmt.Errorf("create rule list dir: %w", err)
+	}
+	return dir, nil
+}
+
+// writeRuleListFile writes one path per line and returns the path as the
+// target's restic binary must be given it. restic.exe reads the lines
+// itself, so /mnt/c-style lines get the translation interop would have
+// applied to them as arguments.
+func writeRuleListFile(dir string, target string, kind string, paths []string) (string, error) {
+	listPath := filepath.Join(dir, fmt.Sprintf("%s.%s.txt", target, kind))
+	if target == "windows" {
+		translator := pathTranslatorLoader()
+		translated := make([]string, 0, len(paths))
+		for _, rulePath := range paths {
+			if !isLinuxAbsolutePath(rulePath) {
+				translated = append(translated, rulePath)
+				continue
+			}
+			if windowsPath, ok := translator.ToWindows(rulePath); ok {
+				rulePath = windowsPath
+			}
+			translated = append(translated, rulePath)
+		}
+		paths = translated
+	}
+	if err := os.WriteFile(listPath, []byte(strings.Join(paths, "\n")+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("write %s list for %s: %w", kind, target, err)
+	}
+	if target != "windows" {
+		return listPath, nil
+	}
+	windowsPath, ok := pathTranslatorLoader().ToWindows(listPath)
+	if !ok {
+		return "", fmt.Errorf("cannot translate %s list path %s for restic.exe", kind, listPath)
+	}
+	return windowsPath, nil
+}
@@ -0,0 +1,439 @@
+package backup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+type ScheduleFile struct {
+	Name    string
+	Content string
+}
+
+type cadenceSchedule struct {
+	cadence       string
+	onCalendar    string
+	startBoundary string
+	trigger       string
+}
+
+// Cadences start at staggered hours so a monthly run does not begin while
+// the daily run of the same night is still going.
+var cadenceSchedules = []cadenceSchedule{
+	{
+		cadence:       "daily",
+		onCalendar:    "*-*-* 02:00:00",
+		startBoundary: "2024-01-01T02:00:00",
+		trigger:       "      <ScheduleByDay>\n        <DaysInterval>1</DaysInterval>\n      </ScheduleByDay>",
+	},
+	{
+		cadence:       "weekly",
+		onCalendar:    "Sun *-*-* 03:00:00",
+		startBoundary: "2024-01-07T03:00:00",
+		trigger:       "      <ScheduleByWeek>\n        <DaysOfWeek>\n          <Sunday />\n        </DaysOfWeek>\n        <WeeksInterval>1</WeeksInterval>\n      </ScheduleByWeek>",
+	},
+	{
+		cadence:       "monthly",
+		onCalendar:    "*-*-01 04:00:00",
+		startBoundary: "2024-01-01T04:00:00",
+		trigger:       "      <ScheduleByMonth>\n        <DaysOfMonth>\n          <Day>1</Day>\n        </DaysOfMonth>\n        <Months>\n          <January />\n          <February />\n          <March />\n          <April />\n          <May />\n          <June />\n          <July />\n          <August />\n          <September />\n          <October />\n          <November />\n          <December />\n        </Months>\n      </ScheduleByMonth>",
+	},
+}
+
+var executablePathResolver = os.Executable
+
+func SetExecutablePathForTests(resolver func() (string, error)) {
+	if resolver == nil {
+		executablePathResolver = os.Executable
+		return
+	}
+	executablePathResolver = resolver
+}
+
+func systemdUnitName(cadence string, kind string) string {
+	return fmt.Sprintf("backup-%s.%s", cadence, kind)
+}
+
+func WindowsTaskName(cadence string) string {
+	return `backup\` + cadence
+}
+
+// ResolveScheduleEnvironmentFile is where scheduled runs read restic
+// credentials from (RESTIC_PASSWORD_FILE, RESTIC_PASSWORD_COMMAND, ...);
+// timers and tasks do not inherit an interactive shell's environment.
+func ResolveScheduleEnvironmentFile() (string, error) {
+	configPath, err := ResolveConfigPath(RuntimeWSL)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "restic.env"), nil
+}
+
+func BuildSystemdUnits(executablePath string, environmentFile string) []ScheduleFile {
+	files := make([]ScheduleFile, 0, len(cadenceSchedules)*2)
+	for _, schedule := range cadenceSchedules {
+		serviceLines := []string{
+			"[Unit]",
+			fmt.Sprintf("Description=backup run %s", schedule.cadence),
+			"",
+			"[Service]",
+			"Type=oneshot",
+		}
+		if environmentFile != "" {
+			// The leading "-" keeps the unit working before the file exists.
+			serviceLines = append(serviceLines, fmt.Sprintf("EnvironmentFile=-%s", systemdQuote(environmentFile)))
+		}
+		service := strings.Join(append(serviceLines,
+			fmt.Sprintf("ExecStart=%s run %s", systemdQuote(executablePath), schedule.cadence),
+			"",
+		), "\n")
+		timer := strings.Join([]string{
+			"[Unit]",
+			fmt.Sprintf("Description=Schedule backup run %s", schedule.cadence),
+			"",
+			"[Timer]",
+			fmt.Sprintf("OnCalendar=%s", schedule.onCalendar),
+			"Persistent=true",
+			"RandomizedDelaySec=10m",
+			fmt.Sprintf("Unit=%s", systemdUnitName(schedule.cadence, "service")),
+			"",
+			"[Install]",
+			"WantedBy=timers.target",
+			"",
+		}, "\n")
+		files = append(files,
+			ScheduleFile{Name: systemdUnitName(schedule.cadence, "service"), Content: service},
+			ScheduleFile{Name: systemdUnitName(schedule.cadence, "timer"), Content: timer},
+		)
+	}
+	return files
+}
+
+func systemdQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	return `"` + escaped + `"`
+}
+
+// windowsArgQuote quotes one argument for the Windows command-line parser
+// wsl.exe uses for its own options.
+func windowsArgQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// shellQuote single-quotes a value for the POSIX shell wsl.exe hands the
+// command after "--" to.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellDoubleQuote quotes a path inside the /bin/sh -c script, which is
+// itself single-quoted.
+func shellDoubleQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// BuildWindowsTaskXML runs the backup through /bin/sh so the task loads the
+// same credentials file the systemd service reads.
+func BuildWindowsTaskXML(cadence string, distroName string, executablePath string, environmentFile string) (string, error) {
+	for _, schedule := range cadenceSchedules {
+		if schedule.cadence != cadence {
+			continue
+		}
+		script := fmt.Sprintf("exec %s run %s", shellDoubleQuote(executablePath), cadence)
+		if environmentFile != "" {
+			script = fmt.Sprintf("if [ -r %[1]s ]; then set -a; . %[1]s; set +a; fi; %[2]s", shellDoubleQuote(environmentFile), script)
+		}
+		arguments := fmt.Sprintf("-d %s -- /bin/sh -c %s", windowsArgQuote(distroName), shellQuote(script))
+		return strings.Join([]string{
+			`<?xml version="1.0" encoding="UTF-16"?>`,
+			`<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">`,
+			"  <RegistrationInfo>",
+			fmt.Sprintf("    <Description>backup run %s in WSL distro %s</Description>", xmlEscape(cadence), xmlEscape(distroName)),
+			"  </RegistrationInfo>",
+			"  <Triggers>",
+			"    <CalendarTrigger>",
+			fmt.Sprintf("      <StartBoundary>%s</StartBoundary>", schedule.startBoundary),
+			"      <Enabled>true</Enabled>",
+			schedule.trigger,
+			"    </CalendarTrigger>",
+			"  </Triggers>",
+			"  <Principals>",
+			`    <Principal id="Author">`,
+			"      <LogonType>InteractiveToken</LogonType>",
+			"      <RunLevel>LeastPrivilege</RunLevel>",
+			"    </Principal>",
+			"  </Principals>",
+			"  <Settings>",
+			"    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>",
+			"    <DisallowStartIfOnBatteries>false</DisallowStartIfOnBatteries>",
+			"    <StopIfGoingOnBatteries>false</StopIfGoingOnBatteries>",
+			"    <StartWhenAvailable>true</StartWhenAvailable>",
+			"    <RunOnlyIfNetworkAvailable>false</RunOnlyIfNetworkAvailable>",
+			"    <ExecutionTimeLimit>PT12H</ExecutionTimeLimit>",
+			"  </Settings>",
+			`  <Actions Context="Author">`,
+			"    <Exec>",
+			"      <Command>wsl.exe</Command>",
+			fmt.Sprintf("      <Arguments>%s</Arguments>", xmlEscape(arguments)),
+			"    </Exec>",
+			"  </Actions>",
+			"</Task>",
+			"",
+		}, "\n"), nil
+	}
+	return "", fmt.Errorf("invalid cadence: %s", cadence)
+}
+
+func xmlEscape(value string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+	return replacer.Replace(value)
+}
+
+// encodeUTF16LE matches the encoding declared in the task XML; schtasks.exe
+// rejects UTF-8 files that claim to be UTF-16.
+func encodeUTF16LE(content string) []byte {
+	units := utf16.Encode([]rune(content))
+	encoded := make([]byte, 2, 2+len(units)*2)
+	binary.LittleEndian.PutUint16(encoded, 0xFEFF)
+	for _, unit := range units {
+		encoded = binary.LittleEndian.AppendUint16(encoded, unit)
+	}
+	return encoded
+}
+
+func ResolveSystemdUserDir() (string, error) {
+	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
+		return filepath.Join(xdgConfig, "systemd", "user"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "systemd", "user"), nil
+}
+
+func resolveWindowsTaskDir() (string, error) {
+	configPath, err := ResolveConfigPath(RuntimeWSL)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "schedule"), nil
+}
+
+func scheduleTimerNames() []string {
+	names := make([]string, 0, len(cadenceSchedules))
+	for _, schedule := range cadenceSchedules {
+		names = append(names, systemdUnitName(schedule.cadence, "timer"))
+	}
+	return names
+}
+
+// InstallSchedule installs either systemd user timers or, with --windows,
+// Task Scheduler entries instead; both at once would run every cadence
+// twice. Switching to tasks removes timers left by an earlier install.
+func InstallSchedule(includeWindows bool, executor Executor) (string, error) {
+	executablePath, err := executablePathResolver()
+	if err != nil {
+		return "", fmt.Errorf("resolve backup executable: %w", err)
+	}
+	environmentFile, err := ResolveScheduleEnvironmentFile()
+	if err != nil {
+		return "", err
+	}
+	if includeWindows {
+		return installWindowsTasks(executablePath, environmentFile, executor)
+	}
+
+	unitDir, err := ResolveSystemdUserDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(unitDir, 0o755); err != nil {
+		return "", fmt.Errorf("create systemd user dir: %w", err)
+	}
+	for _, file := range BuildSystemdUnits(executablePath, environmentFile) {
+		if err := os.WriteFile(filepath.Join(unitDir, file.Name), []byte(file.Content), 0o644); err != nil {
+			return "", fmt.Errorf("write %s: %w", file.Name, err)
+		}
+	}
+	if _, err := executor.Run("systemctl", "--user", "daemon-reload"); err != nil {
+		return "", fmt.Errorf("reload systemd user units: %w", err)
+	}
+	enableArgs := append([]string{"--user", "enable", "--now"}, scheduleTimerNames()...)
+	if _, err := executor.Run("systemctl", enableArgs...); err != nil {
+		return "", fmt.Errorf("enable backup timers: %w", err)
+	}
+
+	return joinLines([]string{
+		fmt.Sprintf("installed systemd user units in %s", unitDir),
+		fmt.Sprintf("enabled timers: %s", strings.Join(scheduleTimerNames(), ", ")),
+		fmt.Sprintf("restic credentials for scheduled runs are read from %s", environmentFile),
+	}), nil
+}
+
+func installWindowsTasks(executablePath string, environmentFile string, executor Executor) (string, error) {
+	distroName := os.Getenv("WSL_DISTRO_NAME")
+	if distroName == "" {
+		return "", fmt.Errorf("WSL_DISTRO_NAME is not set; cannot register Windows tasks")
+	}
+	taskDir, err := resolveWindowsTaskDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(taskDir, 0o755); err != nil {
+		return "", fmt.Errorf("create task dir: %w", err)
+	}
+	lines := make([]string, 0)
+	translator := pathTranslatorLoader()
+	for _, schedule := range cadenceSchedules {
+		content, err := BuildWindowsTaskXML(schedule.cadence, distroName, executablePath, environmentFile)
+		if err != nil {
+			return "", err
+		}
+		taskPath := filepath.Join(taskDir, fmt.Sprintf("backup-%s.xml", schedule.cadence))
+		if err := os.WriteFile(taskPath, encodeUTF16LE(content), 0o644); err != nil {
+			return "", fmt.Errorf("write task xml: %w", err)
+		}
+		windowsTaskPath, ok := translator.ToWindows(taskPath)
+		if !ok {
+			return "", fmt.Errorf("cannot translate task xml path for windows: %s", taskPath)
+		}
+		if _, err := executor.Run("schtasks.exe", "/Create", "/TN", WindowsTaskName(schedule.cadence), "/XML", windowsTaskPath, "/F"); err != nil {
+			return "", fmt.Errorf("register windows task %s: %w", WindowsTaskName(schedule.cadence), err)
+		}
+		lines = append(lines, fmt.Sprintf("registered windows task %s", WindowsTaskName(schedule.cadence)))
+	}
+
+	unitDir, removed, err := removeSystemdUnits(executor)
+	if err != nil {
+		return "", err
+	}
+	if removed > 0 {
+		lines = append(lines, fmt.Sprintf("removed %d systemd user unit(s) from %s; the windows tasks replace them", removed, unitDir))
+	}
+	lines = append(lines, fmt.Sprintf("restic credentials for scheduled runs are read from %s", environmentFile))
+	return joinLines(lines), nil
+}
+
+func RemoveSchedule(includeWindows bool, executor Executor) (string, error) {
+	unitDir, removed, err := removeSystemdUnits(executor)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{fmt.Sprintf("removed %d systemd user unit(s) from %s", removed, unitDir)}
+	if !includeWindows {
+		return joinLines(lines), nil
+	}
+
+	for _, schedule := range cadenceSchedules {
+		taskName := WindowsTaskName(schedule.cadence)
+		if _, err := executor.Run("schtasks.exe", "/Delete", "/TN", taskName, "/F"); err != nil {
+			lines = append(lines, fmt.Sprintf("windows task %s: not removed (%v)", taskName, err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("removed windows task %s", taskName))
+	}
+	if taskDir, err := resolveWindowsTaskDir(); err == nil {
+		_ = os.RemoveAll(taskDir)
+	}
+	return joinLines(lines), nil
+}
+
+func removeSystemdUnits(executor Executor) (string, int, error) {
+	unitDir, err := ResolveSystemdUserDir()
+	if err != nil {
+		return "", 0, err
+	}
+
+	installedTimers := make([]string, 0)
+	for _, timerName := range scheduleTimerNames() {
+		if _, err := os.Stat(filepath.Join(unitDir, timerName)); err == nil {
+			installedTimers = append(installedTimers, timerName)
+		}
+	}
+	if len(installedTimers) > 0 {
+		disableArgs := append([]string{"--user", "disable", "--now"}, installedTimers...)
+		if _, err := executor.Run("systemctl", disableArgs...); err != nil {
+			return "", 0, fmt.Errorf("disable backup timers: %w", err)
+		}
+	}
+
+	removed := 0
+	for _, file := range BuildSystemdUnits("", "") {
+		err := os.Remove(filepath.Join(unitDir, file.Name))
+		if err == nil {
+			removed++
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return "", 0, fmt.Errorf("remove %s: %w", file.Name, err)
+		}
+	}
+	if removed > 0 {
+		if _, err := executor.Run("systemctl", "--user", "daemon-reload"); err != nil {
+			return "", 0, fmt.Errorf("reload systemd user units: %w", err)
+		}
+	}
+
+	return unitDir, removed, nil
+}
+
+func ScheduleStatus(includeWindows bool, executor Executor) (string, error) {
+	unitDir, err := ResolveSystemdUserDir()
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{fmt.Sprintf("systemd user units: %s", unitDir)}
+	for _, schedule := range cadenceSchedules {
+		timerName := systemdUnitName(schedule.cadence, "timer")
+		if _, err := os.Stat(filepath.Join(unitDir, timerName)); err != nil {
+			lines = append(lines, fmt.Sprintf("  %s: not installed", schedule.cadence))
+			continue
+		}
+		enabled := systemctlState(executor, "is-enabled", timerName, "disabled")
+		active := systemctlState(executor, "is-active", timerName, "inactive")
+		lines = append(lines, fmt.Sprintf("  %s: %s, %s (%s)", schedule.cadence, enabled, active, schedule.onCalendar))
+	}
+	if !includeWindows {
+		return joinLines(lines), nil
+	}
+
+	lines = append(lines, "windows tasks:")
+	for _, schedule := range cadenceSchedules {
+		taskName := WindowsTaskName(schedule.cadence)
+		output, err := executor.Run("schtasks.exe", "/Query", "/TN", taskName, "/FO", "LIST")
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("  %s: not registered", taskName))
+			continue
+		}
+		detail := "registered"
+		for _, line := range strings.Split(output, "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "Next Run Time:") {
+				detail += ", next run " + strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "Next Run Time:"))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", taskName, detail))
+	}
+	return joinLines(lines), nil
+}
+
+// systemctl is-enabled/is-active exit non-zero for disabled or inactive
+// units, so a failure maps to the negative state rather than an error.
+func systemctlState(executor Executor, query string, unitName string, fallback string) string {
+	output, err := executor.Run("systemctl", "--user", query, unitName)
+	if err != nil || strings.TrimSpace(output) == "" {
+		return fallback
+	}
+	return strings.TrimSpace(output)
+}
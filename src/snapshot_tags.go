@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Version is stamped at build time with -ldflags "-X wsl-backup-cli/src.Version=...".
+var Version = "dev"
+
+const (
+	TagPrefixCadence = "cadence:"
+	TagPrefixProfile = "profile:"
+	TagPrefixVersion = "backup-cli:"
+
+	// TagPreRestore marks the safety snapshot an in-place restore takes of
+	// the files it is about to overwrite.
+	TagPreRestore = "pre-restore"
+)
+
+var hostnameResolver = os.Hostname
+
+func SetHostnameForTests(resolver func() (string, error)) {
+	if resolver == nil {
+		hostnameResolver = os.Hostname
+		return
+	}
+	hostnameResolver = resolver
+}
+
+func validateSnapshotTags(tags []string) error {
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" || strings.Contains(tag, ",") {
+			return fmt.Errorf("invalid tag %q (tags must be non-empty and must not contain commas)", tag)
+		}
+		for _, prefix := range []string{TagPrefixCadence, TagPrefixProfile, TagPrefixVersion} {
+			if strings.HasPrefix(tag, prefix) {
+				return fmt.Errorf("invalid tag %q (the %s prefix is reserved)", tag, prefix)
+			}
+		}
+	}
+	return nil
+}
+
+func SnapshotTags(cadence string, target string, profile ProfileConfig) []string {
+	tags := []string{TagPrefixCadence + cadence, TagPrefixProfile + target, TagPrefixVersion + Version}
+	return append(tags, profile.Tags...)
+}
+
+// SnapshotHost pins restic's --host so parent snapshot detection survives
+// hostname changes such as a renamed or re-imported WSL distro. Without a
+// configured host it returns "" and restic keeps its own default, unless the
+// profile opts into a short and/or lowercased machine name.
+func SnapshotHost(profile ProfileConfig) string {
+	if profile.Host != "" {
+		return profile.Host
+	}
+	if !profile.HostShortName && !profile.HostLowercase {
+		return ""
+	}
+	hostname, err := hostnameResolver()
+	if err != nil {
+		return ""
+	}
+	hostname = strings.TrimSpace(hostname)
+	if profile.HostShortName {
+		hostname, _, _ = strings.Cut(hostname, ".")
+	}
+	if profile.HostLowercase {
+		hostname = strings.ToLower(hostname)
+	}
+	return hostname
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Snapshot struct {
+	ID       string    `json:"id"`
+	ShortID  string    `json:"short_id"`
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Tags     []string  `json:"tags"`
+	Paths    []string  `json:"paths"`
+}
+
+// Cadence reads the cadence tag written on every backup; snapshots taken
+// before tagging return "".
+func (snapshot Snapshot) Cadence() string {
+	for _, tag := range snapshot.Tags {
+		if strings.HasPrefix(tag, TagPrefixCadence) {
+			return strings.TrimPrefix(tag, TagPrefixCadence)
+		}
+	}
+	return ""
+}
+
+// browseRepository returns the Linux restic binary and repository location
+// for reading a profile's snapshots from the WSL side. A Windows repository
+// path is reached through its WSL mount.
+func browseRepository(profileName string, config AppConfig) (string, string, error) {
+	profile, ok := config.Profiles[profileName]
+	if !ok {
+		return "", "", fmt.Errorf("missing profile config: %s", profileName)
+	}
+	if profile.RepositoryHint == "" {
+		return "", "", fmt.Errorf("missing repository for target: %s", profileName)
+	}
+	repository := profile.RepositoryHint
+	if translated, ok := pathTranslatorLoader().ToWSL(repository); ok && profileName == "windows" {
+		repository = translated.Path
+	}
+	return resticExecutable("wsl", config.Profiles["wsl"]), repository, nil
+}
+
+func ListSnapshots(executable string, repository string, tags []string, executor Executor) ([]Snapshot, error) {
+	args := []string{"-r", repository, "snapshots", "--json"}
+	for _, tag := range tags {
+		args = append(args, "--tag", tag)
+	}
+	output, err := executor.Run(executable, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots in %s: %w", repository, err)
+	}
+	snapshots := make([]Snapshot, 0)
+	if strings.TrimSpace(output) == "" {
+		return snapshots, nil
+	}
+	if err := json.Unmarshal([]byte(output), &snapshots); err != nil {
+		return nil, fmt.Errorf("parse snapshots in %s: %w", repository, err)
+	}
+	sort.Slice(snapshots, func(left int, right int) bool {
+		return snapshots[left].Time.Before(snapshots[right].Time)
+	})
+	return snapshots, nil
+}
+
+// SelectSnapshot picks the newest snapshot with the given cadence tag that
+// was taken on or before the given day (YYYY-MM-DD, local time). Empty
+// filters match everything.
+func SelectSnapshot(snapshots []Snapshot, cadence string, date string) (Snapshot, bool, error) {
+	var before time.Time
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return Snapshot{}, false, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", date)
+		}
+		before = day.AddDate(0, 0, 1)
+	}
+	for index := len(snapshots) - 1; index >= 0; index-- {
+		snapshot := snapshots[index]
+		if cadence != "" && snapshot.Cadence() != cadence {
+			continue
+		}
+		if !before.IsZero() && !snapshot.Time.Before(before) {
+			continue
+		}
+		return snapshot, true, nil
+	}
+	return Snapshot{}, false, nil
+}
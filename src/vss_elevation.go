@@ -0,0 +1,500 @@
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	VSSElevationFallback = "fallback"
+	VSSElevationTask     = "task"
+)
+
+const ElevatedTaskName = `backup\restic-elevated`
+
+const (
+	defaultElevatedTaskPollInterval = 2 * time.Second
+	defaultElevatedTaskTimeout      = 24 * time.Hour
+)
+
+const elevationCheckScript = `([Security.Principal.WindowsPrincipal][Security.Principal.WindowsIdentity]::GetCurrent()).IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)`
+
+// elevatedRunnerPath is where the installer puts the runner. The elevated
+// installer recreates %ProgramData%\backup writable only by Administrators
+// and SYSTEM, so an unelevated process cannot change what the task runs.
+const elevatedRunnerPath = `%ProgramData%\backup\restic-elevated.ps1`
+
+// elevatedRunnerTemplate runs the request.json left in the request
+// directory and writes back output.txt and then exit.txt. The request comes
+// from a user-writable directory, so only the restic.exe pinned at install
+// time may run, only for "restic -r <local or UNC repository> backup" with
+// the flags BuildResticInvocations emits, and only with the forwarded restic
+// variables. Anything that could start another program elevated, such as
+// -o rclone.program, an sftp repository or a password command, is refused.
+const elevatedRunnerTemplate = `$ErrorActionPreference = 'Continue'
+$restic = %s
+$dir = %s
+$allowedEnv = @(%s)
+$switchFlags = @(%s)
+$valueFlags = @(%s)
+function Complete($output, $code) {
+  Set-Content -Encoding UTF8 -Path (Join-Path $dir 'output.txt') -Value $output
+  Set-Content -Encoding ASCII -Path (Join-Path $dir 'exit.txt') -Value $code
+  exit
+}
+$request = Get-Content -Raw -Encoding UTF8 -Path (Join-Path $dir 'request.json') | ConvertFrom-Json
+$requested = (Get-Command -CommandType Application -Name $request.executable -ErrorAction SilentlyContinue | Select-Object -First 1).Source
+if ($requested -ne $restic) { Complete "rejected: $($request.executable) is not $restic" 1 }
+$resticArgs = @($request.args)
+if ($resticArgs.Count -lt 3 -or $resticArgs[0] -cne '-r' -or $resticArgs[2] -cne 'backup') { Complete 'rejected: only restic -r <repository> backup runs elevated' 1 }
+if ($resticArgs[1] -notmatch '^([A-Za-z]:\\|\\\\[^\\])') { Complete "rejected: repository $($resticArgs[1]) is not a local drive or UNC path" 1 }
+for ($i = 3; $i -lt $resticArgs.Count; $i++) {
+  $arg = [string]$resticArgs[$i]
+  if ($switchFlags -ccontains $arg) { continue }
+  if ($valueFlags -ccontains $arg) {
+    if ($i + 1 -ge $resticArgs.Count) { Complete "rejected: $arg needs a value" 1 }
+    $i++
+    continue
+  }
+  if ($arg.StartsWith('-')) { Complete "rejected: $arg is not accepted elevated" 1 }
+}
+if ($request.env) {
+  foreach ($entry in $request.env.PSObject.Properties) {
+    if ($allowedEnv -notcontains $entry.Name) { Complete "rejected: $($entry.Name) is not accepted elevated" 1 }
+    Set-Item -Path ('env:' + $entry.Name) -Value $entry.Value
+  }
+}
+$output = & $restic @resticArgs 2>&1 | Out-String
+$code = $LASTEXITCODE
+if ($null -eq $code) { $code = 1 }
+Complete $output $code
+`
+
+// The flags BuildResticInvocations and SpillRuleLists put after "backup";
+// the elevated runner accepts nothing else.
+var (
+	elevatedSwitchFlags = []string{"--json", "--use-fs-snapshot", "--exclude-caches", "--one-file-system"}
+	elevatedValueFlags  = []string{"--host", "--tag", "--exclude", "--iexclude", "--exclude-file", "--files-from-verbatim", "--exclude-if-present", "--exclude-larger-than", "--compression", "--limit-upload", "--limit-download", "--read-concurrency", "--pack-size"}
+)
+
+// elevatedRunnerInstallScript recreates the runner directory, so no
+// unelevated owner or leftover file survives, before restricting it and
+// writing the runner.
+const elevatedRunnerInstallScript = `$ErrorActionPreference = 'Stop'
+$dir = Join-Path $env:ProgramData 'backup'
+if (Test-Path -LiteralPath $dir) { Remove-Item -LiteralPath $dir -Recurse -Force }
+New-Item -ItemType Directory -Path $dir | Out-Null
+& icacls.exe $dir /inheritance:r /grant:r '*S-1-5-32-544:(OI)(CI)F' '*S-1-5-18:(OI)(CI)F' '*S-1-5-32-545:(OI)(CI)RX' | Out-Null
+if ($LASTEXITCODE -ne 0) { throw "icacls failed for $dir" }
+Set-Content -Encoding UTF8 -LiteralPath (Join-Path $dir 'restic-elevated.ps1') -Value %s
+`
+
+const elevatedRunnerRemoveScript = `$dir = Join-Path $env:ProgramData 'backup'; if (Test-Path -LiteralPath $dir) { Remove-Item -LiteralPath $dir -Recurse -Force -ErrorAction Stop }`
+
+func isValidVSSElevation(mode string) bool {
+	return mode == "" || mode == VSSElevationFallback || mode == VSSElevationTask
+}
+
+// IsWindowsElevated reports whether Windows processes started from this WSL
+// session run with an administrator token, which VSS snapshots require.
+func IsWindowsElevated(executor Executor) (bool, error) {
+	output, err := targetExecutor("windows", executor).Run("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", elevationCheckScript)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(strings.TrimSpace(output), "true"), nil
+}
+
+// resolveVSSExecution decides how a windows backup asking for
+// --use-fs-snapshot runs: directly when already elevated, through the
+// elevated task when configured and registered, and otherwise without VSS
+// and with a warning for the run result.
+func resolveVSSExecution(invocation ResticInvocation, profile ProfileConfig, executor Executor) (ResticInvocation, Executor, []string) {
+	if invocation.Target != "windows" || !containsValue(invocation.Args, "--use-fs-snapshot") {
+		return invocation, executor, nil
+	}
+	elevated, err := IsWindowsElevated(executor)
+	if err == nil && elevated {
+		return invocation, executor, nil
+	}
+
+	warnings := make([]string, 0, 2)
+	if profile.VSSElevation == VSSElevationTask {
+		taskExecutor, taskErr := NewElevatedTaskExecutor(executor)
+		if taskErr == nil {
+			return invocation, taskExecutor, nil
+		}
+		warnings = append(warnings, fmt.Sprintf("warning: %s: elevated task unavailable: %v", invocation.Target, taskErr))
+	}
+
+	reason := "not elevated"
+	if err != nil {
+		reason = fmt.Sprintf("could not check elevation (%v)", err)
+	}
+	withoutSnapshot := make([]string, 0, len(invocation.Args))
+	for _, arg := range invocation.Args {
+		if arg != "--use-fs-snapshot" {
+			withoutSnapshot = append(withoutSnapshot, arg)
+		}
+	}
+	invocation.Args = withoutSnapshot
+	warnings = append(warnings, fmt.Sprintf("warning: %s: %s; backed up without --use-fs-snapshot (VSS), so files held open may be skipped. Run from an elevated shell or set vss_elevation: task", invocation.Target, reason))
+	return invocation, executor, warnings
+}
+
+// ElevatedTaskExecutor runs a Windows command through the pre-registered
+// elevated task: it leaves a request in Dir, starts the task on demand and
+// waits for the runner to write back the exit code.
+type ElevatedTaskExecutor struct {
+	Executor     Executor
+	TaskName     string
+	Dir          string
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+type elevatedRequest struct {
+	Executable string            `json:"executable"`
+	Args       []string          `json:"args"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+type elevatedExitError struct {
+	code    int
+	message string
+}
+
+func (err *elevatedExitError) Error() string { return err.message }
+
+func (err *elevatedExitError) ExitCode() int { return err.code }
+
+func elevatedTaskDir() (string, error) {
+	stateDir, err := ResolveStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "elevated"), nil
+}
+
+func NewElevatedTaskExecutor(executor Executor) (ElevatedTaskExecutor, error) {
+	dir, err := elevatedTaskDir()
+	if err != nil {
+		return ElevatedTaskExecutor{}, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ElevatedTaskExecutor{}, fmt.Errorf("create elevated task dir: %w", err)
+	}
+	// schtasks.exe runs untranslated, as in schedule.go: interop would read
+	// switches such as /Query as Linux paths.
+	if interop, ok := executor.(InteropExecutor); ok {
+		executor = interop.Executor
+	}
+	if _, err := executor.Run("schtasks.exe", "/Query", "/TN", ElevatedTaskName); err != nil {
+		return ElevatedTaskExecutor{}, fmt.Errorf("task %s is not registered: %w", ElevatedTaskName, err)
+	}
+	return ElevatedTaskExecutor{
+		Executor:     executor,
+		TaskName:     ElevatedTaskName,
+		Dir:          dir,
+		PollInterval: defaultElevatedTaskPollInterval,
+		Timeout:      defaultElevatedTaskTimeout,
+	}, nil
+}
+
+func (executor ElevatedTaskExecutor) Run(name string, args ...string) (string, error) {
+	requestPath := filepath.Join(executor.Dir, "request.json")
+	outputPath := filepath.Join(executor.Dir, "output.txt")
+	exitPath := filepath.Join(executor.Dir, "exit.txt")
+	for _, stale := range []string{outputPath, exitPath} {
+		if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("clear elevated task result: %w", err)
+		}
+	}
+
+	// The runner hands these straight to restic.exe, so Linux paths get the
+	// translation InteropExecutor would have applied.
+	toWindows := NewInteropExecutor(executor.Executor).toWindows
+	_, args = PrepareInteropCommand(CommandOptions{}, args, toWindows, "", nil)
+	if isLinuxAbsolutePath(name) {
+		if windowsName, ok := toWindows(name); ok {
+			name = windowsName
+		}
+	}
+
+	if err := validateElevatedArgs(args); err != nil {
+		return "", fmt.Errorf("elevated task %s: %w", executor.TaskName, err)
+	}
+
+	request, err := json.Marshal(elevatedRequest{Executable: name, Args: args, Env: elevatedRequestEnv()})
+	if err != nil {
+		return "", fmt.Errorf("encode elevated task request: %w", err)
+	}
+	// The request can carry repository credentials; keep it private and
+	// short-lived.
+	if err := os.WriteFile(requestPath, request, 0o600); err != nil {
+		return "", fmt.Errorf("write elevated task request: %w", err)
+	}
+	defer os.Remove(requestPath)
+
+	if _, err := executor.Executor.Run("schtasks.exe", "/Run", "/TN", executor.TaskName); err != nil {
+		return "", fmt.Errorf("start elevated task %s: %w", executor.TaskName, err)
+	}
+
+	deadline := time.Now().Add(executor.Timeout)
+	var exitData []byte
+	for {
+		exitData, err = os.ReadFile(exitPath)
+		if err == nil {
+			break
+		}
+		if executor.Timeout > 0 && time.Now().After(deadline) {
+			return "", fmt.Errorf("elevated task %s did not finish within %s", executor.TaskName, executor.Timeout)
+		}
+		time.Sleep(executor.PollInterval)
+	}
+
+	outputData, _ := os.ReadFile(outputPath)
+	output := strings.TrimSpace(strings.TrimPrefix(DecodeConsoleOutput(outputData), "\ufeff"))
+	code, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(string(exitData), "\ufeff")))
+	if err != nil {
+		return "", fmt.Errorf("elevated task %s wrote an unreadable exit code %q", executor.TaskName, strings.TrimSpace(string(exitData)))
+	}
+	if code != 0 {
+		return "", &elevatedExitError{code: code, message: fmt.Sprintf("%s exited with code %d in elevated task %s: %s", name, code, executor.TaskName, output)}
+	}
+	return output, nil
+}
+
+// validateElevatedArgs applies the runner's checks before a request is
+// written, so a refused backup fails with a readable error instead of a
+// rejection from the task.
+func validateElevatedArgs(args []string) error {
+	if len(args) < 3 || args[0] != "-r" || args[2] != "backup" {
+		return fmt.Errorf("only restic -r <repository> backup runs elevated")
+	}
+	if !isElevatedRepositoryPath(args[1]) {
+		return fmt.Errorf("repository %s is not a local drive or UNC path", args[1])
+	}
+	for index := 3; index < len(args); index++ {
+		arg := args[index]
+		if containsValue(elevatedSwitchFlags, arg) {
+			continue
+		}
+		if containsValue(elevatedValueFlags, arg) {
+			if index+1 >= len(args) {
+				return fmt.Errorf("%s needs a value", arg)
+			}
+			index++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("%s is not accepted elevated", arg)
+		}
+	}
+	return nil
+}
+
+// isElevatedRepositoryPath accepts C:\... and \\server\... paths, which restic
+// opens with its local backend.
+func isElevatedRepositoryPath(value string) bool {
+	if len(value) < 3 {
+		return false
+	}
+	if isASCIILetter(value[0]) && value[1] == ':' && value[2] == '\\' {
+		return true
+	}
+	return strings.HasPrefix(value, `\\`) && value[2] != '\\'
+}
+
+// elevatedEnvAllowed keeps the repository in the checked -r argument and
+// refuses a password command, which would run elevated.
+func elevatedEnvAllowed(name string) bool {
+	return name != "RESTIC_PASSWORD_COMMAND" && !strings.HasPrefix(name, "RESTIC_REPOSITORY")
+}
+
+// elevatedRequestEnv hands the forwarded restic variables to the runner,
+// which does not inherit this process's environment.
+func elevatedRequestEnv() map[string]string {
+	env := map[string]string{}
+	translator := pathTranslatorLoader()
+	for _, forwarded := range interopForwardedEnv {
+		name, flag, _ := strings.Cut(forwarded, "/")
+		value := os.Getenv(name)
+		if value == "" || !elevatedEnvAllowed(name) {
+			continue
+		}
+		if flag == "p" {
+			if windowsPath, ok := translator.ToWindows(value); ok {
+				value = windowsPath
+			}
+		}
+		env[name] = value
+	}
+	return env
+}
+
+func BuildElevatedTaskXML(runnerPath string) string {
+	arguments := fmt.Sprintf(`-NoProfile -NonInteractive -ExecutionPolicy Bypass -WindowStyle Hidden -File "%s"`, runnerPath)
+	return strings.Join([]string{
+		`<?xml version="1.0" encoding="UTF-16"?>`,
+		`<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">`,
+		"  <RegistrationInfo>",
+		"    <Description>backup: run restic.exe elevated for VSS snapshots on demand</Description>",
+		"  </RegistrationInfo>",
+		"  <Triggers />",
+		"  <Principals>",
+		`    <Principal id="Author">`,
+		"      <LogonType>InteractiveToken</LogonType>",
+		"      <RunLevel>HighestAvailable</RunLevel>",
+		"    </Principal>",
+		"  </Principals>",
+		"  <Settings>",
+		"    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>",
+		"    <DisallowStartIfOnBatteries>false</DisallowStartIfOnBatteries>",
+		"    <StopIfGoingOnBatteries>false</StopIfGoingOnBatteries>",
+		"    <AllowStartOnDemand>true</AllowStartOnDemand>",
+		"    <ExecutionTimeLimit>PT24H</ExecutionTimeLimit>",
+		"  </Settings>",
+		`  <Actions Context="Author">`,
+		"    <Exec>",
+		"      <Command>powershell.exe</Command>",
+		fmt.Sprintf("      <Arguments>%s</Arguments>", xmlEscape(arguments)),
+		"    </Exec>",
+		"  </Actions>",
+		"</Task>",
+		"",
+	}, "\n")
+}
+
+// BuildElevatedRunnerScript renders the runner with the restic.exe it may
+// run and the Windows path of the request directory.
+func BuildElevatedRunnerScript(resticPath string, requestDir string) string {
+	allowedEnv := make([]string, 0, len(interopForwardedEnv))
+	for _, forwarded := range interopForwardedEnv {
+		name, _, _ := strings.Cut(forwarded, "/")
+		if elevatedEnvAllowed(name) {
+			allowedEnv = append(allowedEnv, powershellQuote(name))
+		}
+	}
+	return fmt.Sprintf(elevatedRunnerTemplate, powershellQuote(resticPath), powershellQuote(requestDir), strings.Join(allowedEnv, ", "), powershellList(elevatedSwitchFlags), powershellList(elevatedValueFlags))
+}
+
+func powershellList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, powershellQuote(value))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func powershellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// resolveElevatedResticPath pins the windows profile's restic.exe to a full
+// Windows path, the only executable the runner will accept.
+func resolveElevatedResticPath(config AppConfig, executor Executor) (string, error) {
+	executable := resticExecutable("windows", config.Profiles["windows"])
+	if isLinuxAbsolutePath(executable) {
+		windowsPath, ok := pathTranslatorLoader().ToWindows(executable)
+		if !ok {
+			return "", fmt.Errorf("cannot translate restic path for windows: %s", executable)
+		}
+		executable = windowsPath
+	}
+	command := fmt.Sprintf("(Get-Command -CommandType Application -Name %s -ErrorAction Stop | Select-Object -First 1).Source", powershellQuote(executable))
+	output, err := targetExecutor("windows", executor).Run("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", command)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", executable, err)
+	}
+	resolved := strings.TrimSpace(output)
+	if resolved == "" {
+		return "", fmt.Errorf("resolve %s: not found", executable)
+	}
+	return resolved, nil
+}
+
+func encodePowerShellCommand(script string) string {
+	return base64.StdEncoding.EncodeToString(encodeUTF16LE(script)[2:])
+}
+
+// InstallElevatedTask registers the on-demand elevated task. Creating a task
+// that runs with highest privileges, and writing its runner under
+// %ProgramData%, needs an elevated shell once.
+func InstallElevatedTask(config AppConfig, executor Executor) (string, error) {
+	elevated, err := IsWindowsElevated(executor)
+	if err != nil {
+		return "", fmt.Errorf("check elevation: %w", err)
+	}
+	if !elevated {
+		return "", fmt.Errorf("registering %s needs an elevated shell; start the WSL terminal as administrator and rerun", ElevatedTaskName)
+	}
+	resticPath, err := resolveElevatedResticPath(config, executor)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := elevatedTaskDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create elevated task dir: %w", err)
+	}
+	translator := pathTranslatorLoader()
+	windowsRequestDir, ok := translator.ToWindows(dir)
+	if !ok {
+		return "", fmt.Errorf("cannot translate elevated task dir for windows: %s", dir)
+	}
+	installScript := fmt.Sprintf(elevatedRunnerInstallScript, powershellQuote(BuildElevatedRunnerScript(resticPath, windowsRequestDir)))
+	if _, err := targetExecutor("windows", executor).Run("powershell.exe", "-NoProfile", "-NonInteractive", "-EncodedCommand", encodePowerShellCommand(installScript)); err != nil {
+		return "", fmt.Errorf("write runner script: %w", err)
+	}
+
+	taskDir, err := resolveWindowsTaskDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(taskDir, 0o755); err != nil {
+		return "", fmt.Errorf("create task dir: %w", err)
+	}
+	taskPath := filepath.Join(taskDir, "backup-elevated.xml")
+	if err := os.WriteFile(taskPath, encodeUTF16LE(BuildElevatedTaskXML(elevatedRunnerPath)), 0o644); err != nil {
+		return "", fmt.Errorf("write task xml: %w", err)
+	}
+	windowsTaskPath, ok := translator.ToWindows(taskPath)
+	if !ok {
+		return "", fmt.Errorf("cannot translate task xml path for windows: %s", taskPath)
+	}
+	if _, err := executor.Run("schtasks.exe", "/Create", "/TN", ElevatedTaskName, "/XML", windowsTaskPath, "/F"); err != nil {
+		return "", fmt.Errorf("register windows task %s: %w", ElevatedTaskName, err)
+	}
+	return fmt.Sprintf("registered windows task %s for %s (set vss_elevation: task on the windows profile to use it)", ElevatedTaskName, resticPath), nil
+}
+
+func RemoveElevatedTask(executor Executor) string {
+	line := fmt.Sprintf("removed windows task %s", ElevatedTaskName)
+	if _, err := executor.Run("schtasks.exe", "/Delete", "/TN", ElevatedTaskName, "/F"); err != nil {
+		line = fmt.Sprintf("windows task %s: not removed (%v)", ElevatedTaskName, err)
+	}
+	if _, err := targetExecutor("windows", executor).Run("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", elevatedRunnerRemoveScript); err != nil {
+		line += fmt.Sprintf("; %s not removed (%v)", elevatedRunnerPath, err)
+	}
+	if dir, err := elevatedTaskDir(); err == nil {
+		_ = os.RemoveAll(dir)
+	}
+	return line
+}
+
+func ElevatedTaskStatus(executor Executor) string {
+	if _, err := executor.Run("schtasks.exe", "/Query", "/TN", ElevatedTaskName); err != nil {
+		return fmt.Sprintf("  %s: not registered", ElevatedTaskName)
+	}
+	return fmt.Sprintf("  %s: registered", ElevatedTaskName)
+}
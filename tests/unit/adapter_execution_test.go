@@ -2,6 +2,8 @@ package unit
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	backup "wsl-backup-cli/src"
@@ -130,3 +132,180 @@ func TestBuildRestoreInvocationUsesLatestAndTarget(t *testing.T) {
 		t.Fatalf("unexpected restore target args: %#v", invocation.Args)
 	}
 }
+
+type versionExecutor struct {
+	versions map[string]string
+	calls    []string
+	mutex    sync.Mutex
+}
+
+func (executor *versionExecutor) Run(name string, args ...string) (string, error) {
+	executor.mutex.Lock()
+	executor.calls = append(executor.calls, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	executor.mutex.Unlock()
+	if len(args) == 1 && args[0] == "version" {
+		version, ok := executor.versions[name]
+		if !ok {
+			return "", fmt.Errorf("executable file not found: %s", name)
+		}
+		return fmt.Sprintf("restic %s compiled with go1.23.3 on linux/amd64", version), nil
+	}
+	return "ok", nil
+}
+
+func TestBuildResticInvocationsUsesConfiguredResticPath(t *testing.T) {
+	t.Parallel()
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/test"}},
+			RepositoryHint:   "/repo",
+			ResticPath:       "/opt/restic/bin/restic",
+		},
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\test`}},
+			RepositoryHint:   `C:\repo`,
+		},
+	}}
+
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	if invocations[0].Executable != "/opt/restic/bin/restic" {
+		t.Fatalf("expected configured restic path, got %q", invocations[0].Executable)
+	}
+	if invocations[1].Executable != "restic.exe" {
+		t.Fatalf("expected default restic.exe, got %q", invocations[1].Executable)
+	}
+}
+
+func TestPreflightResticVersionsAcceptsPinnedVersion(t *testing.T) {
+	t.Parallel()
+
+	executor := &versionExecutor{versions: map[string]string{
+		"restic":     backup.PinnedResticVersion,
+		"restic.exe": backup.PinnedResticVersion,
+	}}
+	invocations := []backup.ResticInvocation{
+		{Target: "wsl", Executable: "restic"},
+		{Target: "windows", Executable: "restic.exe"},
+	}
+
+	if err := backup.PreflightResticVersions(invocations, backup.AppConfig{}, executor); err != nil {
+		t.Fatalf("PreflightResticVersions returned error: %v", err)
+	}
+	if len(executor.calls) != 2 {
+		t.Fatalf("unexpected calls: %#v", executor.calls)
+	}
+}
+
+func TestPreflightResticVersionsRejectsMismatch(t *testing.T) {
+	t.Parallel()
+
+	executor := &versionExecutor{versions: map[string]string{
+		"restic":     backup.PinnedResticVersion,
+		"restic.exe": "0.16.4",
+	}}
+	invocations := []backup.ResticInvocation{
+		{Target: "wsl", Executable: "restic"},
+		{Target: "windows", Executable: "restic.exe"},
+	}
+
+	err := backup.PreflightResticVersions(invocations, backup.AppConfig{}, executor)
+	if err == nil {
+		t.Fatal("expected version mismatch error")
+	}
+	if !strings.Contains(err.Error(), "restic version mismatch for windows (restic.exe): found 0.16.4") {
+		t.Fatalf("unexpected error: %q", err.Error())
+	}
+}
+
+func TestPreflightResticVersionsHonorsMinimumVersion(t *testing.T) {
+	t.Parallel()
+
+	executor := &versionExecutor{versions: map[string]string{"restic": "0.18.0"}}
+	invocations := []backup.ResticInvocation{{Target: "wsl", Executable: "restic"}}
+
+	if err := backup.PreflightResticVersions(invocations, backup.AppConfig{ResticMinVersion: "0.17.0"}, executor); err != nil {
+		t.Fatalf("expected newer version to satisfy minimum, got %v", err)
+	}
+
+	err := backup.PreflightResticVersions(invocations, backup.AppConfig{ResticMinVersion: "0.18.1"}, executor)
+	if err == nil || !strings.Contains(err.Error(), "required >= 0.18.1") {
+		t.Fatalf("expected minimum version error, got %v", err)
+	}
+}
+
+func TestBuildResticInvocationsTagsSnapshotsAndPinsHost(t *testing.T) {
+	t.Parallel()
+
+	plan := backup.RunPlan{Cadence: "weekly", Targets: []string{"wsl", "windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Weekly: []string{"/home/test"}},
+			RepositoryHint:   "/repo",
+			Host:             "workstation",
+			Tags:             []string{"laptop"},
+		},
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Weekly: []string{`C:\Users\test`}},
+			RepositoryHint:   `C:\repo`,
+		},
+	}}
+
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	if got := strings.Join(invocations[0].Args, " "); got != "-r /repo backup --json --host workstation --tag cadence:weekly --tag profile:wsl --tag backup-cli:dev --tag laptop /home/test" {
+		t.Fatalf("unexpected wsl args: %q", got)
+	}
+	if got := strings.Join(invocations[1].Args, " "); got != `-r C:\repo backup --json --tag cadence:weekly --tag profile:windows --tag backup-cli:dev C:\Users\test` {
+		t.Fatalf("expected restic's default host for windows, got %q", got)
+	}
+}
+
+func TestSnapshotHostNormalizesMachineNameOnlyWhenAskedTo(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		profile  backup.ProfileConfig
+		expected string
+	}{
+		{profile: backup.ProfileConfig{}, expected: ""},
+		{profile: backup.ProfileConfig{Host: "Workstation.lan", HostShortName: true, HostLowercase: true}, expected: "Workstation.lan"},
+		{profile: backup.ProfileConfig{HostShortName: true}, expected: "TestHost"},
+		{profile: backup.ProfileConfig{HostLowercase: true}, expected: "testhost.example"},
+		{profile: backup.ProfileConfig{HostShortName: true, HostLowercase: true}, expected: "testhost"},
+	}
+	for _, testCase := range cases {
+		if got := backup.SnapshotHost(testCase.profile); got != testCase.expected {
+			t.Fatalf("SnapshotHost(%+v) = %q, want %q", testCase.profile, got, testCase.expected)
+		}
+	}
+}
+
+func TestBuildResticInvocationsRendersBackupOptionsBeforePaths(t *testing.T) {
+	t.Parallel()
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/test"}},
+			ExcludeByCadence: backup.CadencePaths{Daily: []string{"/home/test/.cache"}},
+			RepositoryHint:   "/repo",
+			Host:             "workstation",
+			BackupOptions:    backup.BackupOptions{ExcludeCaches: true, Compression: "auto", ExtraArgs: []string{"--no-scan"}},
+		},
+	}}
+
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	if got := strings.Join(invocations[0].Args, " "); !strings.HasSuffix(got, "--exclude-caches --compression auto --no-scan --exclude /home/test/.cache /home/test") {
+		t.Fatalf("unexpected args: %q", got)
+	}
+}
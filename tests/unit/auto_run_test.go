@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+func successRecords(cadence string, finishedAt time.Time, profiles ...string) []backup.RunRecord {
+	records := make([]backup.RunRecord, 0, len(profiles))
+	for _, profile := range profiles {
+		records = append(records, backup.RunRecord{Cadence: cadence, Profile: profile, StartedAt: finishedAt.Add(-time.Minute), FinishedAt: finishedAt, Success: true})
+	}
+	return records
+}
+
+func TestEvaluateDueCadencesUsesOldestProfileSuccess(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	history := append(successRecords("daily", now.Add(-30*time.Hour), "wsl"), successRecords("daily", now.Add(-2*time.Hour), "windows")...)
+	history = append(history, successRecords("weekly", now.Add(-48*time.Hour), "wsl", "windows")...)
+	history = append(history, backup.RunRecord{Cadence: "monthly", Profile: "wsl", FinishedAt: now.Add(-time.Hour), Success: false})
+
+	evaluations := backup.EvaluateDueCadences(history, []string{"wsl", "windows"}, now)
+	due := map[string]bool{}
+	for _, evaluation := range evaluations {
+		due[evaluation.Cadence] = evaluation.Due
+	}
+	if !due["monthly"] || due["weekly"] || !due["daily"] {
+		t.Fatalf("unexpected due cadences: %#v", evaluations)
+	}
+	if evaluations[0].Cadence != "monthly" || evaluations[2].Cadence != "daily" {
+		t.Fatalf("expected widest cadence first, got %#v", evaluations)
+	}
+}
+
+func TestApplyCadenceSupersessionRequiresSamePaths(t *testing.T) {
+	t.Parallel()
+
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/me/docs"}, Weekly: []string{"/home/me", "/srv"}, Monthly: []string{"/home/me"}},
+			ExcludeByCadence: backup.CadencePaths{Daily: []string{"/home/me/tmp"}, Weekly: []string{}, Monthly: []string{"/home/me/tmp"}},
+		},
+	}}
+	evaluations := []backup.CadenceDue{
+		{Cadence: "monthly", Due: true},
+		{Cadence: "weekly", Due: true},
+		{Cadence: "daily", Due: true},
+	}
+
+	updated := backup.ApplyCadenceSupersession(evaluations, []string{"wsl"}, config)
+	if updated[1].SupersededBy != "" {
+		t.Fatalf("weekly includes /srv and must still run: %#v", updated[1])
+	}
+	if updated[2].SupersededBy != "monthly" {
+		t.Fatalf("daily should be covered by monthly: %#v", updated[2])
+	}
+}
+
+func TestRunAutoExecutesOnlyOverdueCadences(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	setupOverlapRun(t, "overlap_policy: \"off\"\n")
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	backup.SetClockForTests(func() time.Time { return now })
+	t.Cleanup(func() { backup.SetClockForTests(nil) })
+
+	history := append(successRecords("weekly", now.Add(-24*time.Hour), "wsl", "windows"), successRecords("monthly", now.Add(-72*time.Hour), "wsl", "windows")...)
+	history = append(history, successRecords("daily", now.Add(-26*time.Hour), "wsl", "windows")...)
+	if err := backup.AppendRunHistory(history); err != nil {
+		t.Fatalf("AppendRunHistory returned error: %v", err)
+	}
+
+	command, err := backup.ParseArgs([]string{"run", "auto"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	executor := &versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": backup.PinnedResticVersion}}
+	output, err := backup.Run(command, executor)
+	if err != nil {
+		t.Fatalf("run auto returned error: %v", err)
+	}
+	if !strings.Contains(output, "daily backup run executed") || strings.Contains(output, "weekly backup run executed") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	if !strings.Contains(output, "  monthly: not due (last success 2026-03-07T09:00:00Z)") {
+		t.Fatalf("expected due summary in output, got %q", output)
+	}
+
+	records, err := backup.LoadRunHistory()
+	if err != nil {
+		t.Fatalf("LoadRunHistory returned error: %v", err)
+	}
+	if len(records) != len(history)+2 {
+		t.Fatalf("expected two new history records, got %d", len(records)-len(history))
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "history.jsonl")); err != nil {
+		t.Fatalf("expected history file in state dir: %v", err)
+	}
+
+	output, err = backup.Run(command, executor)
+	if err != nil {
+		t.Fatalf("second run auto returned error: %v", err)
+	}
+	if !strings.Contains(output, "nothing due.") {
+		t.Fatalf("expected nothing due after catching up, got %q", output)
+	}
+}
@@ -89,7 +89,7 @@ func TestParseArgsRestoreRejectsOptions(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error")
 	}
-	if err.Error() != "restore does not accept options" {
+	if err.Error() != "unknown restore option: extra" {
 		t.Fatalf("unexpected error: %q", err.Error())
 	}
 }
@@ -264,3 +264,191 @@ func TestRunTestInvokesRunner(t *testing.T) {
 		t.Fatalf("unexpected output: %q", output)
 	}
 }
+
+func TestRunReportExcludedShowsPresetOrigins(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    include:\n      - /home/test\n    exclude_presets:\n      - vm-images\n    exclude:\n      - /home/test/tmp\n  windows:\n    repository: C:\\\\repo\n    include:\n      - C:\\\\Users\\\\test\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+	t.Cleanup(func() {
+		backup.SetRuntimeDetectorForTests(nil)
+		backup.SetDevContainerDetectorForTests(nil)
+	})
+	backup.SetRuntimeDetectorForTests(func() backup.Runtime { return backup.RuntimeWSL })
+	backup.SetDevContainerDetectorForTests(func() bool { return false })
+
+	output, err := backup.Run(backup.Command{Name: "report", Cadence: "weekly", Report: "excluded"}, &fakeExecutor{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(output, "presets: vm-images") {
+		t.Fatalf("expected preset summary, got %q", output)
+	}
+	if !strings.Contains(output, "**/*.vhdx (preset: vm-images)") {
+		t.Fatalf("expected preset-annotated exclude, got %q", output)
+	}
+	if !strings.Contains(output, "  - /home/test/tmp\n") {
+		t.Fatalf("expected inline exclude, got %q", output)
+	}
+}
+
+func TestParseArgsConfigShow(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"config", "show"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Name != "config" || command.Action != "show" {
+		t.Fatalf("unexpected command: %#v", command)
+	}
+
+	if _, err := backup.ParseArgs([]string{"config", "edit"}); err == nil {
+		t.Fatal("expected unknown config action error")
+	}
+}
+
+func TestParseArgsRunOverlapOption(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"run", "daily", "--overlap=warn"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Overlap != "warn" {
+		t.Fatalf("unexpected command: %#v", command)
+	}
+
+	if _, err := backup.ParseArgs([]string{"run", "daily", "--overlap=loose"}); err == nil || err.Error() != "invalid overlap policy: loose" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := backup.ParseArgs([]string{"run", "daily", "--verbose"}); err == nil || err.Error() != "unknown run option: --verbose" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func setupOverlapRun(t *testing.T, extraConfig string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte(extraConfig + "profiles:\n  wsl:\n    repository: /repo/wsl\n    include:\n      - /mnt/c/Users/me/Projects\n  windows:\n    repository: C:\\repo\\windows\n    include:\n      - C:\\Users\\me\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+	t.Cleanup(func() {
+		backup.SetRuntimeDetectorForTests(nil)
+		backup.SetDevContainerDetectorForTests(nil)
+	})
+	backup.SetRuntimeDetectorForTests(func() backup.Runtime { return backup.RuntimeWSL })
+	backup.SetDevContainerDetectorForTests(func() bool { return false })
+}
+
+func TestRunOverlapWarnPolicyPrintsWarningsOnSuccess(t *testing.T) {
+	setupOverlapRun(t, "overlap_policy: warn\n")
+
+	executor := &versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": backup.PinnedResticVersion}}
+	output, err := backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor)
+	if err != nil {
+		t.Fatalf("expected warn policy to succeed, got %v", err)
+	}
+	if !strings.Contains(output, "daily backup run executed") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	if !strings.Contains(output, `warning: platform include overlap detected: wsl=/mnt/c/Users/me/Projects overlaps windows=C:\Users\me`) {
+		t.Fatalf("expected overlap warning in output, got %q", output)
+	}
+}
+
+func TestRunOverlapFlagOverridesConfigPolicy(t *testing.T) {
+	setupOverlapRun(t, "overlap_policy: warn\n")
+
+	_, err := backup.Run(backup.Command{Name: "run", Cadence: "daily", Overlap: "strict"}, &fakeExecutor{})
+	if err == nil || !strings.Contains(err.Error(), "platform include overlap detected in strict mode") {
+		t.Fatalf("expected strict overlap error, got %v", err)
+	}
+}
+
+func TestRunStrictOverlapHonorsAllowedOverlaps(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    include:\n      - /mnt/c/Users/me/Projects\n    allowed_overlaps:\n      - /mnt/c/Users/me/Projects\n  windows:\n    repository: C:\\repo\\windows\n    include:\n      - C:\\Users\\me\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+	t.Cleanup(func() {
+		backup.SetRuntimeDetectorForTests(nil)
+		backup.SetDevContainerDetectorForTests(nil)
+	})
+	backup.SetRuntimeDetectorForTests(func() backup.Runtime { return backup.RuntimeWSL })
+	backup.SetDevContainerDetectorForTests(func() bool { return false })
+
+	executor := &versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": backup.PinnedResticVersion}}
+	output, err := backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor)
+	if err != nil {
+		t.Fatalf("expected allowed overlap to pass strict mode, got %v", err)
+	}
+	if strings.Contains(output, "warning:") {
+		t.Fatalf("allowed overlaps should not warn, got %q", output)
+	}
+}
+
+func TestRunDryRunShowsDedupeExcludes(t *testing.T) {
+	setupOverlapRun(t, "overlap_policy: dedupe\noverlap_winner: wsl\n")
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	command, err := backup.ParseArgs([]string{"run", "daily", "--dry-run"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	executor := &fakeExecutor{}
+	output, err := backup.Run(command, executor)
+	if err != nil {
+		t.Fatalf("dry run returned error: %v", err)
+	}
+	for _, expected := range []string{
+		"dry run: daily backup run platforms=wsl,windows (steps=2).",
+		`  windows: restic.exe -r C:\repo\windows backup --json --tag cadence:daily --tag profile:windows --tag backup-cli:dev --exclude C:\Users\me\Projects C:\Users\me`,
+		`  dedupe: /mnt/c/Users/me/Projects owned by wsl; windows excludes C:\Users\me\Projects`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output, got %q", expected, output)
+		}
+	}
+	if len(executor.calls) != 0 {
+		t.Fatalf("dry run must not execute restic, got %#v", executor.calls)
+	}
+}
+
+func TestRunDedupeSkipsProfileWithEveryIncludeDropped(t *testing.T) {
+	setupOverlapRun(t, "overlap_policy: dedupe\noverlap_winner: windows\n")
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	output, err := backup.Run(backup.Command{Name: "run", Cadence: "daily", DryRun: true}, &fakeExecutor{})
+	if err != nil {
+		t.Fatalf("dry run returned error: %v", err)
+	}
+	for _, expected := range []string{
+		"dry run: daily backup run platforms=windows (steps=1).",
+		"dedupe: skipped wsl daily backup; every include is owned by windows",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output, got %q", expected, output)
+		}
+	}
+
+	executor := &versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": backup.PinnedResticVersion}}
+	output, err = backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor)
+	if err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if !strings.Contains(output, "daily backup run executed for platforms=windows (steps=1).") || !strings.Contains(output, "dedupe: skipped wsl daily backup") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+}
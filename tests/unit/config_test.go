@@ -3,6 +3,7 @@ package unit
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	backup "wsl-backup-cli/src"
@@ -220,3 +221,390 @@ func TestLoadConfigAutoDiscoversDefaultRuleFiles(t *testing.T) {
 		t.Fatalf("unexpected daily exclude paths: %#v", profile.ExcludeByCadence.Daily)
 	}
 }
+
+func TestLoadConfigRuleFilesSupportIncludeDirectiveAndNegation(t *testing.T) {
+	tempDir := t.TempDir()
+	rulesDir := filepath.Join(tempDir, "rules")
+	sharedDir := filepath.Join(rulesDir, "shared")
+	if err := os.MkdirAll(sharedDir, 0o755); err != nil {
+		t.Fatalf("mkdir shared dir: %v", err)
+	}
+
+	write := func(path string, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	write(filepath.Join(sharedDir, "caches.txt"), "**/node_modules\n**/.cache\n")
+	write(filepath.Join(rulesDir, "wsl.exclude.daily.txt"), "@include shared/caches.txt\n!/home/test/.cache/keep\n")
+	write(filepath.Join(rulesDir, "wsl.include.daily.txt"), "/home/test\n!/home/test/scratch\n")
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    exclude:\n      - /home/test/tmp\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	profile := config.Profiles["wsl"]
+	if len(profile.IncludeByCadence.Daily) != 1 || profile.IncludeByCadence.Daily[0] != "/home/test" {
+		t.Fatalf("unexpected daily include paths: %#v", profile.IncludeByCadence.Daily)
+	}
+	expectedExcludes := []string{"/home/test/tmp", "**/node_modules", "**/.cache", "!/home/test/.cache/keep", "/home/test/scratch"}
+	if len(profile.ExcludeByCadence.Daily) != len(expectedExcludes) {
+		t.Fatalf("unexpected daily exclude paths: %#v", profile.ExcludeByCadence.Daily)
+	}
+	for index, expected := range expectedExcludes {
+		if profile.ExcludeByCadence.Daily[index] != expected {
+			t.Fatalf("unexpected daily exclude paths: %#v", profile.ExcludeByCadence.Daily)
+		}
+	}
+}
+
+func TestLoadConfigRuleFileIncludeCycleFails(t *testing.T) {
+	tempDir := t.TempDir()
+	rulesDir := filepath.Join(tempDir, "rules")
+	if err := os.MkdirAll(rulesDir, 0o755); err != nil {
+		t.Fatalf("mkdir rules dir: %v", err)
+	}
+
+	write := func(path string, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	write(filepath.Join(rulesDir, "wsl.exclude.daily.txt"), "@include a.txt\n")
+	write(filepath.Join(rulesDir, "a.txt"), "/home/test/a\n@include b.txt\n")
+	write(filepath.Join(rulesDir, "b.txt"), "@include a.txt\n")
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil {
+		t.Fatal("expected include cycle error")
+	}
+	if !strings.Contains(err.Error(), "rule file include cycle") {
+		t.Fatalf("unexpected error: %q", err.Error())
+	}
+}
+
+func TestLoadConfigRuleFileMissingIncludeFails(t *testing.T) {
+	tempDir := t.TempDir()
+	rulesDir := filepath.Join(tempDir, "rules")
+	if err := os.MkdirAll(rulesDir, 0o755); err != nil {
+		t.Fatalf("mkdir rules dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "wsl.exclude.daily.txt"), []byte("@include missing.txt\n"), 0o644); err != nil {
+		t.Fatalf("write rule file: %v", err)
+	}
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil {
+		t.Fatal("expected missing include error")
+	}
+	if !strings.Contains(err.Error(), "missing.txt") {
+		t.Fatalf("unexpected error: %q", err.Error())
+	}
+}
+
+func TestLoadConfigMergesExcludePresets(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    exclude_presets:\n      - dev-caches\n    exclude:\n      - /home/test/tmp\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	presetPatterns, ok := backup.ExcludePresetPatterns("dev-caches")
+	if !ok {
+		t.Fatal("expected dev-caches preset")
+	}
+	profile := config.Profiles["wsl"]
+	for _, excludes := range [][]string{profile.ExcludeByCadence.Daily, profile.ExcludeByCadence.Weekly, profile.ExcludeByCadence.Monthly} {
+		if len(excludes) != len(presetPatterns)+1 {
+			t.Fatalf("unexpected exclude paths: %#v", excludes)
+		}
+		if excludes[0] != presetPatterns[0] || excludes[len(excludes)-1] != "/home/test/tmp" {
+			t.Fatalf("unexpected exclude order: %#v", excludes)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownExcludePreset(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    exclude_presets:\n      - not-a-preset\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil {
+		t.Fatal("expected unknown preset error")
+	}
+	if !strings.Contains(err.Error(), "unknown exclude preset: not-a-preset") {
+		t.Fatalf("unexpected error: %q", err.Error())
+	}
+}
+
+func TestLoadConfigLayersSystemUserAndFragments(t *testing.T) {
+	tempDir := t.TempDir()
+	systemDir := filepath.Join(tempDir, "etc")
+	userDir := filepath.Join(tempDir, "user")
+	fragmentDir := filepath.Join(userDir, "config.d")
+	for _, dir := range []string{systemDir, fragmentDir, filepath.Join(systemDir, "rules")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+
+	write := func(path string, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	systemPath := filepath.Join(systemDir, "config.yaml")
+	userPath := filepath.Join(userDir, "config.yaml")
+	write(systemPath, "profiles:\n  wsl:\n    repository: /repo/org\n    exclude_presets:\n      - dev-caches\n    exclude_files:\n      daily: rules/org.exclude.txt\n")
+	write(filepath.Join(systemDir, "rules", "org.exclude.txt"), "/home/test/org-cache\n")
+	write(userPath, "profiles:\n  wsl:\n    include:\n      - /home/test\n")
+	write(filepath.Join(fragmentDir, "10-repo.yaml"), "profiles:\n  wsl:\n    repository: /repo/first\n")
+	write(filepath.Join(fragmentDir, "20-repo.yaml"), "profiles:\n  wsl:\n    repository: /repo/second\n")
+
+	t.Setenv("BACKUP_SYSTEM_CONFIG", systemPath)
+	t.Setenv("BACKUP_CONFIG", userPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	if len(config.Layers) != 4 || config.Layers[0] != systemPath || config.Layers[1] != userPath {
+		t.Fatalf("unexpected layers: %#v", config.Layers)
+	}
+	profile := config.Profiles["wsl"]
+	if profile.RepositoryHint != "/repo/second" {
+		t.Fatalf("expected last fragment to win, got %q", profile.RepositoryHint)
+	}
+	if len(profile.IncludeByCadence.Daily) != 1 || profile.IncludeByCadence.Daily[0] != "/home/test" {
+		t.Fatalf("unexpected daily include paths: %#v", profile.IncludeByCadence.Daily)
+	}
+	daily := profile.ExcludeByCadence.Daily
+	if len(daily) == 0 || daily[len(daily)-1] != "/home/test/org-cache" {
+		t.Fatalf("expected system rule file resolved next to system config, got %#v", daily)
+	}
+	if source := config.Sources["profiles.wsl.repository"]; source != filepath.Join(fragmentDir, "20-repo.yaml") {
+		t.Fatalf("unexpected repository source: %q", source)
+	}
+	if source := config.Sources["profiles.wsl.exclude_presets"]; source != systemPath {
+		t.Fatalf("unexpected exclude_presets source: %q", source)
+	}
+	if source := config.Sources["profiles.wsl.include"]; source != userPath {
+		t.Fatalf("unexpected include source: %q", source)
+	}
+
+	report := backup.BuildConfigShowReport(config)
+	for _, expected := range []string{
+		"    daily include:\n      - /home/test  (from inline " + userPath + ")\n",
+		"      - **/node_modules  (from preset dev-caches)\n",
+		"      - /home/test/org-cache  (from file " + filepath.Join(systemDir, "rules", "org.exclude.txt") + ")\n",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Fatalf("expected %q in report:\n%s", expected, report)
+		}
+	}
+}
+
+func TestLoadConfigSystemLayerAloneMarksConfigExisting(t *testing.T) {
+	tempDir := t.TempDir()
+	systemPath := filepath.Join(tempDir, "system.yaml")
+	if err := os.WriteFile(systemPath, []byte("profiles:\n  wsl:\n    repository: /repo/org\n"), 0o644); err != nil {
+		t.Fatalf("write system config: %v", err)
+	}
+
+	t.Setenv("BACKUP_SYSTEM_CONFIG", systemPath)
+	t.Setenv("BACKUP_CONFIG", filepath.Join(tempDir, "missing", "config.yaml"))
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if !config.Exists {
+		t.Fatal("expected config.Exists true")
+	}
+	if config.Profiles["wsl"].RepositoryHint != "/repo/org" {
+		t.Fatalf("unexpected repository: %q", config.Profiles["wsl"].RepositoryHint)
+	}
+}
+
+func TestLoadConfigParsesResticPathAndVersionPins(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("restic_min_version: \"0.17.0\"\nprofiles:\n  wsl:\n    repository: /repo/wsl\n    restic_path: /opt/restic/bin/restic\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if config.Profiles["wsl"].ResticPath != "/opt/restic/bin/restic" {
+		t.Fatalf("unexpected restic path: %q", config.Profiles["wsl"].ResticPath)
+	}
+	if backup.ResticVersionRequirement(config) != ">= 0.17.0" {
+		t.Fatalf("unexpected version requirement: %q", backup.ResticVersionRequirement(config))
+	}
+}
+
+func TestLoadConfigReadsOverlapPolicyAndAllowedOverlaps(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("overlap_policy: warn\nprofiles:\n  wsl:\n    repository: /repo/wsl\n    allowed_overlaps:\n      - /mnt/c/Users/me/Projects\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if config.OverlapPolicy != "warn" {
+		t.Fatalf("unexpected overlap policy: %q", config.OverlapPolicy)
+	}
+	if got := config.Profiles["wsl"].AllowedOverlaps; len(got) != 1 || got[0] != "/mnt/c/Users/me/Projects" {
+		t.Fatalf("unexpected allowed overlaps: %#v", got)
+	}
+}
+
+func TestLoadConfigRejectsUnknownOverlapPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("overlap_policy: loose\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil || !strings.Contains(err.Error(), `invalid overlap_policy in config: "loose"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadConfigReadsSnapshotTagsAndHost(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    host: workstation\n    tags: [laptop, home]\n  windows:\n    repository: C:\\repo\n    host_short_name: true\n    host_lowercase: true\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	profile := config.Profiles["wsl"]
+	if profile.Host != "workstation" || strings.Join(profile.Tags, ",") != "laptop,home" {
+		t.Fatalf("unexpected host or tags: %q %#v", profile.Host, profile.Tags)
+	}
+	if windows := config.Profiles["windows"]; !windows.HostShortName || !windows.HostLowercase || backup.SnapshotHost(windows) != "testhost" {
+		t.Fatalf("expected opt-in machine host normalization, got %+v", windows)
+	}
+}
+
+func TestLoadConfigRejectsReservedSnapshotTag(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    tags: [\"cadence:hourly\"]\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil || !strings.Contains(err.Error(), `load tags for profile wsl: invalid tag "cadence:hourly"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadConfigReadsBackupOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    backup_options:\n      exclude_caches: true\n      exclude_if_present: [.nobackup]\n      exclude_larger_than: 2G\n      one_file_system: true\n      compression: max\n      limit_upload: 5000\n      read_concurrency: 4\n      pack_size: 64\n      iexclude: [\"*.TMP\"]\n      extra_args: [\"--skip-if-unchanged\"]\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	expected := "--exclude-caches --exclude-if-present .nobackup --exclude-larger-than 2G --one-file-system --compression max --limit-upload 5000 --read-concurrency 4 --pack-size 64 --iexclude *.TMP --skip-if-unchanged"
+	if got := strings.Join(config.Profiles["wsl"].BackupOptions.Args(), " "); got != expected {
+		t.Fatalf("unexpected backup option args: %q", got)
+	}
+}
+
+func TestLoadConfigRejectsInvalidBackupOptions(t *testing.T) {
+	cases := map[string]string{
+		"wsl:\n    repository: /repo/wsl\n    backup_options:\n      compression: huge\n":               `load backup_options for profile wsl: invalid compression "huge"`,
+		"wsl:\n    repository: /repo/wsl\n    backup_options:\n      exclude_larger_than: 2GB\n":        `invalid exclude_larger_than "2GB"`,
+		"windows:\n    repository: C:\\repo\n    backup_options:\n      one_file_system: true\n":        "one_file_system is not supported by restic on Windows",
+		"wsl:\n    repository: /repo/wsl\n    backup_options:\n      extra_args: [\"--repo=/other\"]\n": "extra_args must not set --repo",
+		"wsl:\n    repository: /repo/wsl\n    backup_options:\n      limit_upload: -1\n":                "invalid limit_upload: -1",
+	}
+	for profileYAML, expected := range cases {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte("profiles:\n  "+profileYAML), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		t.Setenv("BACKUP_CONFIG", configPath)
+
+		_, err := backup.LoadConfig(backup.RuntimeWSL)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownVSSElevation(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("profiles:\n  windows:\n    repository: C:\\repo\n    vss_elevation: runas\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	_, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err == nil || !strings.Contains(err.Error(), `invalid vss_elevation for profile windows: "runas"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
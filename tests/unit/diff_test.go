@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+const diffSnapshotsJSON = `[
+  {"id":"aaaa1111","short_id":"aaaa1111","time":"2026-03-01T12:00:00Z","tags":["cadence:monthly"],"paths":["/home/me/docs","/home/me/src"]},
+  {"id":"bbbb2222","short_id":"bbbb2222","time":"2026-03-08T12:00:00Z","tags":["cadence:weekly"],"paths":["/home/me/docs","/home/me/src"]},
+  {"id":"cccc3333","short_id":"cccc3333","time":"2026-03-09T12:00:00Z","tags":["cadence:daily"],"paths":["/home/me/docs","/home/me/src"]}
+]`
+
+func TestRunDiffDefaultsToLastMonthlyAndWeekly(t *testing.T) {
+	t.Parallel()
+
+	config := backup.AppConfig{Exists: true, Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic -r /repo snapshots --json": diffSnapshotsJSON,
+		"restic -r /repo diff --json aaaa1111 bbbb2222": strings.Join([]string{
+			`{"message_type":"change","path":"/home/me/docs/new.txt","modifier":"+"}`,
+			`{"message_type":"change","path":"/home/me/docs/","modifier":"M"}`,
+			`{"message_type":"change","path":"/home/me/src/old.go","modifier":"-"}`,
+			`{"message_type":"change","path":"/home/me/src/main.go","modifier":"M"}`,
+			`{"message_type":"change","path":"/home/me/src/main.go","modifier":"U"}`,
+			`{"message_type":"statistics","changed_files":3}`,
+		}, "\n"),
+		"restic -r /repo ls --json aaaa1111": strings.Join([]string{
+			`{"struct_type":"snapshot","id":"aaaa1111"}`,
+			`{"struct_type":"node","type":"file","path":"/home/me/src/old.go","size":300}`,
+			`{"struct_type":"node","type":"file","path":"/home/me/src/main.go","size":100}`,
+		}, "\n"),
+		"restic -r /repo ls --json bbbb2222": strings.Join([]string{
+			`{"struct_type":"node","type":"file","path":"/home/me/docs/new.txt","size":2048}`,
+			`{"struct_type":"node","type":"file","path":"/home/me/src/main.go","size":120}`,
+		}, "\n"),
+	}}
+
+	output, err := backup.RunDiff(backup.Command{Name: "diff", Profile: "wsl"}, config, executor)
+	if err != nil {
+		t.Fatalf("RunDiff returned error: %v", err)
+	}
+	for _, expected := range []string{
+		"wsl: aaaa1111 (",
+		"monthly) -> bbbb2222 (",
+		"  /home/me/docs: 1 added (2.0 KiB), 0 removed (0 B), 0 modified (0 B)",
+		"  /home/me/src: 0 added (0 B), 1 removed (300 B), 1 modified (120 B)",
+		"  total: 1 added (2.0 KiB), 1 removed (300 B), 1 modified (120 B)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunDiffLiveComparesSnapshotWithDisk(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	writeFile("same.txt", "same")
+	writeFile("changed.txt", "changed content")
+	writeFile("added.txt", "new")
+	writeFile("scratch.tmp", "ignored")
+	info, err := os.Stat(filepath.Join(root, "same.txt"))
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	mtime := info.ModTime().UTC().Format(time.RFC3339Nano)
+
+	config := backup.AppConfig{Exists: true, Profiles: map[string]backup.ProfileConfig{"wsl": {
+		RepositoryHint:   "/repo",
+		ExcludeByCadence: backup.CadencePaths{Daily: []string{"*.tmp"}},
+	}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic -r /repo snapshots --json": fmt.Sprintf(`[{"id":"dddd4444","short_id":"dddd4444","time":"2026-03-09T12:00:00Z","tags":["cadence:daily"],"paths":[%q]}]`, root),
+		"restic -r /repo ls --json dddd4444": strings.Join([]string{
+			fmt.Sprintf(`{"struct_type":"node","type":"file","path":%q,"size":4,"mtime":%q}`, root+"/same.txt", mtime),
+			fmt.Sprintf(`{"struct_type":"node","type":"file","path":%q,"size":4,"mtime":%q}`, root+"/changed.txt", mtime),
+			fmt.Sprintf(`{"struct_type":"node","type":"file","path":%q,"size":10,"mtime":%q}`, root+"/gone.txt", mtime),
+		}, "\n"),
+	}}
+
+	output, err := backup.RunDiff(backup.Command{Name: "diff", Profile: "wsl", Live: true}, config, executor)
+	if err != nil {
+		t.Fatalf("RunDiff returned error: %v", err)
+	}
+	expected := fmt.Sprintf("  %s: 1 added (3 B), 1 removed (10 B), 1 modified (15 B)", root)
+	if !strings.Contains(output, expected) || !strings.Contains(output, "-> live filesystem") {
+		t.Fatalf("expected %q in output:\n%s", expected, output)
+	}
+}
+
+func TestRunDiffLiveMatchesWindowsExcludesLikeRestic(t *testing.T) {
+	mountRoot := t.TempDir()
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator {
+		return backup.NewPathTranslator("[automount]\nroot = "+mountRoot+"/\n", `C:\134 `+mountRoot+"/c drvfs rw,noatime 0 0\n", "Ubuntu")
+	})
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	home := filepath.Join(mountRoot, "c", "Users", "me")
+	for _, name := range []string{"notes.txt", "Downloads/setup.iso", "Downloads/keep/tax.pdf", "project/node_modules/lib.js"} {
+		localPath := filepath.Join(home, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(localPath, []byte("content"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	config := backup.AppConfig{Exists: true, Profiles: map[string]backup.ProfileConfig{"windows": {
+		RepositoryHint:   `C:\repo`,
+		ExcludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\me\Downloads`, `!C:\Users\me\Downloads\keep`, "**/node_modules"}},
+	}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic -r " + mountRoot + "/c/repo snapshots --json": `[{"id":"eeee5555","short_id":"eeee5555","time":"2026-03-09T12:00:00Z","tags":["cadence:daily"],"paths":["C:\\Users\\me"]}]`,
+	}}
+
+	output, err := backup.RunDiff(backup.Command{Name: "diff", Profile: "windows", Live: true}, config, executor)
+	if err != nil {
+		t.Fatalf("RunDiff returned error: %v (calls %#v)", err, executor.calls)
+	}
+	if !strings.Contains(output, "/C/Users/me: 2 added (14 B), 0 removed (0 B), 0 modified (0 B)") {
+		t.Fatalf("expected only notes.txt and the re-included tax.pdf added in output:\n%s", output)
+	}
+}
+
+func TestParseArgsDiff(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"diff", "windows", "aaaa", "--live"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Name != "diff" || command.Profile != "windows" || command.Snapshot != "aaaa" || !command.Live {
+		t.Fatalf("unexpected diff command: %#v", command)
+	}
+	if _, err := backup.ParseArgs([]string{"diff", "wsl", "aaaa", "bbbb", "--live"}); err == nil {
+		t.Fatal("expected two snapshots with --live to be rejected")
+	}
+	if _, err := backup.ParseArgs([]string{"diff", "linux"}); err == nil {
+		t.Fatal("expected invalid profile to be rejected")
+	}
+}
@@ -0,0 +1,171 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+type scriptedExecutor struct {
+	responses map[string]string
+	failures  map[string]string
+	calls     []string
+	mutex     sync.Mutex
+}
+
+func (executor *scriptedExecutor) Run(name string, args ...string) (string, error) {
+	call := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	executor.mutex.Lock()
+	executor.calls = append(executor.calls, call)
+	executor.mutex.Unlock()
+	if message, ok := executor.failures[call]; ok {
+		return "", fmt.Errorf("command failed: exit status 1: %s", message)
+	}
+	if output, ok := executor.responses[call]; ok {
+		return output, nil
+	}
+	return "", nil
+}
+
+func stubDoctorEnvironment(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		backup.SetLookPathForTests(nil)
+		backup.SetDiskFreeProbeForTests(nil)
+	})
+	backup.SetLookPathForTests(func(name string) (string, error) { return "/usr/bin/" + name, nil })
+	backup.SetDiskFreeProbeForTests(func(string) (uint64, error) { return 100 << 30, nil })
+	t.Setenv("RESTIC_PASSWORD", "secret")
+	t.Setenv("RESTIC_PASSWORD_FILE", "")
+	t.Setenv("RESTIC_PASSWORD_COMMAND", "")
+}
+
+func TestRunDoctorChecksAllPass(t *testing.T) {
+	stubDoctorEnvironment(t)
+	includeDir := t.TempDir()
+	repoDir := t.TempDir()
+
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic version": "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on linux/amd64",
+	}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{includeDir}},
+			RepositoryHint:   repoDir,
+		},
+	}}
+
+	checks := backup.RunDoctorChecks([]string{"wsl"}, config, executor)
+	for _, check := range checks {
+		if check.Status != backup.DoctorPass {
+			t.Fatalf("expected all checks to pass, got %#v", check)
+		}
+	}
+	report := backup.FormatDoctorReport(checks)
+	if !strings.Contains(report, "[pass] restic binary: restic "+backup.PinnedResticVersion) {
+		t.Fatalf("unexpected report: %q", report)
+	}
+	if !strings.Contains(report, "[pass] repository locks: no locks") {
+		t.Fatalf("unexpected report: %q", report)
+	}
+}
+
+func TestRunDoctorChecksReportsFailuresWithHints(t *testing.T) {
+	stubDoctorEnvironment(t)
+	repoDir := t.TempDir()
+	missingInclude := filepath.Join(t.TempDir(), "missing")
+
+	executor := &scriptedExecutor{
+		responses: map[string]string{
+			"restic version": "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on linux/amd64",
+			"restic -r " + repoDir + " list locks --no-lock": "1f2e3d4c\n",
+		},
+		failures: map[string]string{
+			"restic.exe version": "not found",
+		},
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{missingInclude}},
+			RepositoryHint:   repoDir,
+		},
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\test`}},
+			RepositoryHint:   `C:\repo`,
+		},
+	}}
+
+	checks := backup.RunDoctorChecks([]string{"wsl", "windows"}, config, executor)
+	report := backup.FormatDoctorReport(checks)
+
+	expectations := []string{
+		"[fail] include path: " + missingInclude,
+		"[warn] repository locks: 1 lock(s) present",
+		"unlock",
+		"[fail] restic binary: run restic.exe version",
+		"[pass] password source: RESTIC_PASSWORD",
+	}
+	for _, expected := range expectations {
+		if !strings.Contains(report, expected) {
+			t.Fatalf("expected %q in report:\n%s", expected, report)
+		}
+	}
+	for _, call := range executor.calls {
+		if strings.HasPrefix(call, "restic.exe -r") {
+			t.Fatalf("repository probe should be skipped when restic.exe is broken, got %q", call)
+		}
+	}
+}
+
+func TestRunDoctorChecksProbesWindowsRepositoryThroughInterop(t *testing.T) {
+	stubDoctorEnvironment(t)
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic.exe version": "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on windows/amd64",
+	}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"windows": {RepositoryHint: "/mnt/d/restic"},
+	}}
+
+	report := backup.FormatDoctorReport(backup.RunDoctorChecks([]string{"windows"}, config, executor))
+	for _, expected := range []string{`restic.exe -r D:\restic cat config`, `restic.exe -r D:\restic list locks --no-lock`} {
+		if !containsString(executor.calls, expected) {
+			t.Fatalf("expected %q in calls %#v", expected, executor.calls)
+		}
+	}
+	if !strings.Contains(report, "[pass] repository: /mnt/d/restic reachable") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+}
+
+func TestRunDoctorChecksClassifiesWrongPassword(t *testing.T) {
+	stubDoctorEnvironment(t)
+	repoDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoDir, "data"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	executor := &scriptedExecutor{
+		responses: map[string]string{
+			"restic version": "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on linux/amd64",
+		},
+		failures: map[string]string{
+			"restic -r " + repoDir + " cat config": "Fatal: wrong password or no key found",
+		},
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {RepositoryHint: repoDir},
+	}}
+
+	report := backup.FormatDoctorReport(backup.RunDoctorChecks([]string{"wsl"}, config, executor))
+	if !strings.Contains(report, "[fail] repository password") {
+		t.Fatalf("expected password failure, got:\n%s", report)
+	}
+}
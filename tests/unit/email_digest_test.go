@@ -0,0 +1,215 @@
+package unit
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+type smtpStandIn struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	commands []string
+	messages []string
+}
+
+// startSMTPStandIn speaks just enough plain SMTP (with AUTH PLAIN) for
+// net/smtp to deliver one message per connection.
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	standIn := &smtpStandIn{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go standIn.serve(connection)
+		}
+	}()
+	return standIn
+}
+
+func (standIn *smtpStandIn) serve(connection net.Conn) {
+	defer connection.Close()
+	reader := bufio.NewReader(connection)
+	write := func(line string) { _, _ = connection.Write([]byte(line + "\r\n")) }
+	write("220 stand-in ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		standIn.mutex.Lock()
+		standIn.commands = append(standIn.commands, command)
+		standIn.mutex.Unlock()
+		verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			write("250-stand-in")
+			write("250 AUTH PLAIN")
+		case "AUTH":
+			write("235 authenticated")
+		case "MAIL", "RCPT":
+			write("250 ok")
+		case "DATA":
+			write("354 send data")
+			var message strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				message.WriteString(dataLine)
+			}
+			standIn.mutex.Lock()
+			standIn.messages = append(standIn.messages, message.String())
+			standIn.mutex.Unlock()
+			write("250 queued")
+		case "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 ok")
+		}
+	}
+}
+
+func (standIn *smtpStandIn) port() int {
+	return standIn.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (standIn *smtpStandIn) snapshot() ([]string, []string) {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	return append([]string{}, standIn.commands...), append([]string{}, standIn.messages...)
+}
+
+func TestSendDigestWaitsForIntervalThenMailsRecentRuns(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+	t.Setenv("BACKUP_SMTP_PASSWORD", "mail-secret")
+	standIn := startSMTPStandIn(t)
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	backup.SetClockForTests(func() time.Time { return now })
+	t.Cleanup(func() { backup.SetClockForTests(nil) })
+
+	config := backup.EmailDigestConfig{
+		Host:        "127.0.0.1",
+		Port:        standIn.port(),
+		Security:    "none",
+		From:        "backup@example.test",
+		To:          []string{"owner@example.test"},
+		Username:    "backup",
+		PasswordEnv: "BACKUP_SMTP_PASSWORD",
+		Interval:    7 * 24 * time.Hour,
+	}
+
+	sent, err := backup.SendDigest(config, false, &scriptedExecutor{})
+	if err != nil || sent {
+		t.Fatalf("first call should only start the period, sent=%t err=%v", sent, err)
+	}
+
+	history := []backup.RunRecord{
+		{Cadence: "daily", Profile: "wsl", FinishedAt: now.Add(-time.Hour), Success: true},
+		{Cadence: "daily", Profile: "wsl", FinishedAt: now.Add(2 * 24 * time.Hour), Success: true},
+		{Cadence: "weekly", Profile: "windows", FinishedAt: now.Add(3 * 24 * time.Hour), Success: false, Error: "repository is locked"},
+	}
+	if err := backup.AppendRunHistory(history); err != nil {
+		t.Fatalf("AppendRunHistory returned error: %v", err)
+	}
+
+	now = now.Add(6 * 24 * time.Hour)
+	if sent, err := backup.SendDigest(config, false, &scriptedExecutor{}); err != nil || sent {
+		t.Fatalf("digest should not be due yet, sent=%t err=%v", sent, err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	sent, err = backup.SendDigest(config, false, &scriptedExecutor{})
+	if err != nil || !sent {
+		t.Fatalf("expected digest to be sent, sent=%t err=%v", sent, err)
+	}
+
+	commands, messages := standIn.snapshot()
+	if len(messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(messages))
+	}
+	message := messages[0]
+	if !strings.Contains(message, "Subject: backup digest for ") || !strings.Contains(message, ": 1 failed, 1 succeeded") {
+		t.Fatalf("unexpected subject in message: %q", message)
+	}
+	if !strings.Contains(message, "FAILED: repository is locked") || strings.Contains(message, "2026-03-01 07:00") {
+		t.Fatalf("digest should list only runs since the last digest: %q", message)
+	}
+	authSeen := false
+	for _, command := range commands {
+		if strings.HasPrefix(command, "AUTH PLAIN ") {
+			authSeen = true
+		}
+	}
+	if !authSeen {
+		t.Fatalf("expected AUTH PLAIN, got %#v", commands)
+	}
+}
+
+func TestRenderDigestUsesTemplateOverride(t *testing.T) {
+	tempDir := t.TempDir()
+	templatePath := filepath.Join(tempDir, "digest.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{range .Records}}{{.Profile}}={{.Success}};{{end}}"), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	config := backup.EmailDigestConfig{Subject: "{{.Failures}} failures", TemplatePath: templatePath}
+	data := backup.DigestData{Records: []backup.RunRecord{{Profile: "wsl", Success: true}, {Profile: "windows"}}, Failures: 1}
+	subject, body, err := backup.RenderDigest(config, data)
+	if err != nil {
+		t.Fatalf("RenderDigest returned error: %v", err)
+	}
+	if subject != "1 failures" || body != "wsl=true;windows=false;" {
+		t.Fatalf("unexpected digest: %q / %q", subject, body)
+	}
+}
+
+func TestLoadConfigReadsEmailDigest(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("notifications:\n  email:\n    host: smtp.example.test\n    tls: tls\n    from: backup@example.test\n    to: [owner@example.test]\n    username: backup\n    password_file: smtp-password\n    template: digest.tmpl\n    interval: 24h\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	email := config.Notifications.Email
+	if email.Port != 465 || email.Security != "tls" || email.Interval != 24*time.Hour {
+		t.Fatalf("unexpected email config: %#v", email)
+	}
+	if email.PasswordFile != filepath.Join(tempDir, "smtp-password") || email.TemplatePath != filepath.Join(tempDir, "digest.tmpl") {
+		t.Fatalf("expected paths relative to config dir: %#v", email)
+	}
+
+	if err := os.WriteFile(configPath, []byte("notifications:\n  email:\n    host: smtp.example.test\n    from: a@example.test\n    to: [b@example.test]\n    tls: ssl\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := backup.LoadConfig(backup.RuntimeWSL); err == nil || !strings.Contains(err.Error(), `invalid email tls mode "ssl"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
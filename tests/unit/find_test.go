@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+const findJSON = `[
+  {"snapshot":"aaaa1111","hits":1,"matches":[{"path":"/home/me/notes.txt","type":"file","size":10,"mtime":"2026-02-20T09:00:00Z"}]},
+  {"snapshot":"bbbb2222","hits":2,"matches":[{"path":"/home/me/notes.txt","type":"file","size":10,"mtime":"2026-02-20T09:00:00Z"},{"path":"/home/me/old","type":"dir","size":0,"mtime":"2026-02-20T09:00:00Z"}]},
+  {"snapshot":"cccc3333","hits":1,"matches":[{"path":"/home/me/notes.txt","type":"file","size":42,"mtime":"2026-03-09T08:00:00Z"}]}
+]`
+
+func TestFindAcrossProfilesMergesVersionsAndWarnsOnFailedProfile(t *testing.T) {
+	t.Parallel()
+
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic -r /repo snapshots --json": snapshotsJSON,
+		"restic -r /repo find --json --snapshot aaaa1111 --snapshot bbbb2222 --snapshot cccc3333 notes.txt": findJSON,
+	}}
+
+	hits, warnings, err := backup.FindAcrossProfiles([]string{"wsl", "windows"}, "notes.txt", backup.FindFilter{}, config, executor)
+	if err != nil {
+		t.Fatalf("FindAcrossProfiles returned error: %v", err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "warning: windows: missing profile config") {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("expected two versions, got %#v", hits)
+	}
+	if hits[0].Size != 42 || hits[0].LastSeen.ID != "cccc3333" || hits[0].Snapshots != 1 {
+		t.Fatalf("expected newest version first, got %#v", hits[0])
+	}
+	if hits[1].Size != 10 || hits[1].FirstSeen.ID != "aaaa1111" || hits[1].LastSeen.ID != "bbbb2222" || hits[1].Snapshots != 2 {
+		t.Fatalf("unexpected merged version: %#v", hits[1])
+	}
+
+	restore := backup.RestoreCommandForHit(hits[1], "/tmp/out")
+	if restore.Name != "restore" || restore.Profile != "wsl" || restore.Snapshot != "bbbb2222" || restore.Include != "/home/me/notes.txt" || restore.Target != "/tmp/out" {
+		t.Fatalf("unexpected restore handoff: %#v", restore)
+	}
+}
+
+func TestFindInProfileFiltersSnapshotsBySinceAndCadence(t *testing.T) {
+	t.Parallel()
+
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic -r /repo snapshots --json --tag cadence:daily": snapshotsJSON,
+	}}
+
+	if _, err := backup.FindInProfile("wsl", "notes.txt", backup.FindFilter{Cadence: "daily", Since: "2026-03-05"}, config, executor); err != nil {
+		t.Fatalf("FindInProfile returned error: %v", err)
+	}
+	last := executor.calls[len(executor.calls)-1]
+	if last != "restic -r /repo find --json --snapshot bbbb2222 --snapshot cccc3333 notes.txt" {
+		t.Fatalf("unexpected find call: %q", last)
+	}
+}
+
+func TestParseArgsFindAndRestoreOptions(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"find", "*.docx", "--profile=windows", "--since=2026-01-01", "--restore=2", "--to=/tmp/out"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Name != "find" || command.Pattern != "*.docx" || command.Profile != "windows" || command.Since != "2026-01-01" || command.Hit != 2 || command.Target != "/tmp/out" {
+		t.Fatalf("unexpected find command: %#v", command)
+	}
+	if _, err := backup.ParseArgs([]string{"find", "*.docx", "--restore=1"}); err == nil {
+		t.Fatal("expected --restore without --to to be rejected")
+	}
+	if _, err := backup.ParseArgs([]string{"find", "*.docx", "--since=2026-13-01"}); err == nil || err.Error() != "invalid --since date: 2026-13-01 (expected YYYY-MM-DD)" {
+		t.Fatalf("expected invalid --since to be rejected, got %v", err)
+	}
+
+	command, err = backup.ParseArgs([]string{"restore", "/tmp/out", "--profile=windows", "--snapshot=abc", "--include=/mnt/c/Users/me/a.docx"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Profile != "windows" || command.Snapshot != "abc" || command.Include != "/mnt/c/Users/me/a.docx" {
+		t.Fatalf("unexpected restore command: %#v", command)
+	}
+}
+
+func TestFormatFindRestoreHintKeepsFiltersAndQuotesPattern(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"find", "it's *.docx", "--profile=windows", "--cadence=weekly", "--since=2026-01-01"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	expected := `restore a hit with: backup find 'it'\''s *.docx' --profile=windows --cadence=weekly --since=2026-01-01 --restore=<n> --to=<dir>`
+	if got := backup.FormatFindRestoreHint(command); got != expected {
+		t.Fatalf("unexpected hint:\n%s\nwant:\n%s", got, expected)
+	}
+	if got := backup.FormatFindRestoreHint(backup.Command{Name: "find", Pattern: "*.pdf"}); got != "restore a hit with: backup find '*.pdf' --restore=<n> --to=<dir>" {
+		t.Fatalf("unexpected hint without filters: %s", got)
+	}
+}
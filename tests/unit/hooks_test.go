@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+type recordedCommand struct {
+	call    string
+	env     []string
+	timeout time.Duration
+}
+
+type optionsExecutor struct {
+	mutex    sync.Mutex
+	failures map[string]bool
+	commands []recordedCommand
+}
+
+func (executor *optionsExecutor) Run(name string, args ...string) (string, error) {
+	return executor.RunWithOptions(backup.CommandOptions{}, name, args...)
+}
+
+func (executor *optionsExecutor) RunWithOptions(options backup.CommandOptions, name string, args ...string) (string, error) {
+	call := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	executor.mutex.Lock()
+	executor.commands = append(executor.commands, recordedCommand{call: call, env: options.Env, timeout: options.Timeout})
+	executor.mutex.Unlock()
+	if executor.failures[call] {
+		return "", fmt.Errorf("command failed: exit status 1")
+	}
+	return "ok", nil
+}
+
+func (executor *optionsExecutor) find(prefix string) (recordedCommand, bool) {
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+	for _, command := range executor.commands {
+		if strings.HasPrefix(command.call, prefix) {
+			return command, true
+		}
+	}
+	return recordedCommand{}, false
+}
+
+func hookTestConfig() backup.AppConfig {
+	return backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			RepositoryHint: "/repo/wsl",
+			Hooks: backup.HookConfig{
+				Pre:       backup.CadencePaths{Daily: []string{"pg_dump app > /srv/dump.sql"}},
+				Post:      backup.CadencePaths{Daily: []string{"rm /srv/dump.sql"}},
+				OnFailure: backup.CadencePaths{Daily: []string{"echo failed"}},
+				Timeout:   time.Minute,
+			},
+		},
+		"windows": {
+			RepositoryHint: `C:\repo`,
+			Hooks: backup.HookConfig{
+				Pre:       backup.CadencePaths{Daily: []string{"Stop-Service app"}},
+				OnFailure: backup.CadencePaths{Daily: []string{"Start-Service app"}},
+				Timeout:   2 * time.Minute,
+			},
+		},
+	}}
+}
+
+func TestExecuteResticInvocationsWithHooksRunsPlatformShells(t *testing.T) {
+	t.Setenv("WSLENV", "USERPROFILE/p")
+	invocations := []backup.ResticInvocation{
+		{Target: "wsl", Executable: "restic", Args: []string{"-r", "/repo/wsl", "backup"}},
+		{Target: "windows", Executable: "restic.exe", Args: []string{"-r", `C:\repo`, "backup"}},
+	}
+	executor := &optionsExecutor{}
+
+	results := backup.ExecuteResticInvocationsWithHooks(invocations, "daily", hookTestConfig(), executor)
+	for _, result := range results {
+		if result.Err != nil || len(result.Warnings) != 0 {
+			t.Fatalf("unexpected result: %#v", result)
+		}
+	}
+
+	pre, ok := executor.find("bash -c pg_dump app")
+	if !ok {
+		t.Fatalf("expected wsl pre hook through bash, got %#v", executor.commands)
+	}
+	if pre.timeout != time.Minute || !containsString(pre.env, "BACKUP_HOOK=pre") || !containsString(pre.env, "BACKUP_PROFILE=wsl") || !containsString(pre.env, "BACKUP_CADENCE=daily") {
+		t.Fatalf("unexpected pre hook options: %#v", pre)
+	}
+	if _, ok := executor.find("bash -c rm /srv/dump.sql"); !ok {
+		t.Fatal("expected post hook after success")
+	}
+
+	windowsPre, ok := executor.find("powershell.exe -NoProfile -NonInteractive -Command Stop-Service app")
+	if !ok {
+		t.Fatalf("expected windows pre hook through powershell.exe, got %#v", executor.commands)
+	}
+	if !containsString(windowsPre.env, "WSLENV=USERPROFILE/p:BACKUP_HOOK:BACKUP_PROFILE:BACKUP_CADENCE:BACKUP_REPOSITORY") {
+		t.Fatalf("expected hook variables forwarded through WSLENV, got %#v", windowsPre.env)
+	}
+	if _, ok := executor.find("powershell.exe -NoProfile -NonInteractive -Command Start-Service app"); ok {
+		t.Fatal("on_failure hook must not run after success")
+	}
+}
+
+func TestExecuteResticInvocationsWithHooksPreFailureAbortsProfile(t *testing.T) {
+	invocations := []backup.ResticInvocation{
+		{Target: "wsl", Executable: "restic", Args: []string{"-r", "/repo/wsl", "backup"}},
+		{Target: "windows", Executable: "restic.exe", Args: []string{"-r", `C:\repo`, "backup"}},
+	}
+	executor := &optionsExecutor{failures: map[string]bool{"powershell.exe -NoProfile -NonInteractive -Command Stop-Service app": true}}
+
+	results := backup.ExecuteResticInvocationsWithHooks(invocations, "daily", hookTestConfig(), executor)
+	if results[0].Err != nil {
+		t.Fatalf("wsl profile should still run: %#v", results[0])
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), `pre hook "Stop-Service app" failed`) {
+		t.Fatalf("expected pre hook failure, got %#v", results[1])
+	}
+	if _, ok := executor.find("restic.exe"); ok {
+		t.Fatal("restic.exe must not run after a failed pre hook")
+	}
+	onFailure, ok := executor.find("powershell.exe -NoProfile -NonInteractive -Command Start-Service app")
+	if !ok || !containsString(onFailure.env, "BACKUP_RUN_STATUS=failure") {
+		t.Fatalf("expected on_failure hook with failure status, got %#v", onFailure)
+	}
+}
+
+func TestLoadConfigReadsHooks(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo/wsl\n    hooks:\n      pre:\n        daily: [\"pg_dump app > /srv/dump.sql\"]\n      post: [\"rm -f /srv/dump.sql\"]\n      timeout: 5m\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	hooks := config.Profiles["wsl"].Hooks
+	if len(hooks.Pre.ForCadence("daily")) != 1 || len(hooks.Pre.ForCadence("weekly")) != 0 {
+		t.Fatalf("unexpected pre hooks: %#v", hooks.Pre)
+	}
+	if len(hooks.Post.ForCadence("monthly")) != 1 || hooks.Timeout != 5*time.Minute {
+		t.Fatalf("unexpected hooks: %#v", hooks)
+	}
+}
+
+func containsString(values []string, expected string) bool {
+	for _, value := range values {
+		if value == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package unit
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+func TestPrepareInteropCommandTranslatesPathsAndForwardsEnv(t *testing.T) {
+	t.Parallel()
+
+	translated := map[string]string{"/mnt/d/restore": `D:\restore`, "/home/me/.cache/restic": `\\wsl.localhost\Ubuntu\home\me\.cache\restic`}
+	toWindows := func(linuxPath string) (string, bool) {
+		windowsPath, ok := translated[linuxPath]
+		return windowsPath, ok
+	}
+	args := []string{"-r", `C:\repo`, "restore", "latest", "--target", "/mnt/d/restore", "--cache-dir=/home/me/.cache/restic", "--exclude", "*.tmp", "/nowhere"}
+	options := backup.CommandOptions{Env: []string{"BACKUP_PROFILE=windows", "BACKUP_LOG=/var/log/backup.log"}}
+	environ := []string{"WSLENV=USERPROFILE/p:BACKUP_PROFILE", "RESTIC_PASSWORD_FILE=/home/me/.restic-pass", "PATH=/usr/bin"}
+
+	prepared, preparedArgs := backup.PrepareInteropCommand(options, args, toWindows, "/mnt/c", environ)
+
+	expectedArgs := []string{"-r", `C:\repo`, "restore", "latest", "--target", `D:\restore`, `--cache-dir=\\wsl.localhost\Ubuntu\home\me\.cache\restic`, "--exclude", "*.tmp", "/nowhere"}
+	if strings.Join(preparedArgs, "|") != strings.Join(expectedArgs, "|") {
+		t.Fatalf("unexpected args: %#v", preparedArgs)
+	}
+	if prepared.Dir != "/mnt/c" {
+		t.Fatalf("expected windows working dir, got %q", prepared.Dir)
+	}
+	expectedEnv := "BACKUP_PROFILE=windows|BACKUP_LOG=/var/log/backup.log|WSLENV=USERPROFILE/p:BACKUP_PROFILE:BACKUP_LOG/p:RESTIC_PASSWORD_FILE/p"
+	if got := strings.Join(prepared.Env, "|"); got != expectedEnv {
+		t.Fatalf("unexpected env: %q", got)
+	}
+}
+
+func TestPrepareInteropCommandKeepsExplicitWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	prepared, _ := backup.PrepareInteropCommand(backup.CommandOptions{Dir: "/mnt/c/Users/me"}, nil, func(string) (string, bool) { return "", false }, "/mnt/c", nil)
+	if prepared.Dir != "/mnt/c/Users/me" || len(prepared.Env) != 0 {
+		t.Fatalf("unexpected options: %#v", prepared)
+	}
+}
+
+func TestDecodeConsoleOutputHandlesUTF16(t *testing.T) {
+	t.Parallel()
+
+	encode := func(text string) []byte {
+		encoded := make([]byte, 0, len(text)*2)
+		for _, unit := range []rune(text) {
+			encoded = append(encoded, byte(unit), byte(unit>>8))
+		}
+		return encoded
+	}
+
+	withBOM := append([]byte{0xFF, 0xFE}, encode("Taskname: \\backup\\daily\r\nStatus: Bereit für Ausführung\r\n")...)
+	if got := backup.DecodeConsoleOutput(withBOM); got != "Taskname: \\backup\\daily\nStatus: Bereit für Ausführung\n" {
+		t.Fatalf("unexpected BOM decode: %q", got)
+	}
+	if got := backup.DecodeConsoleOutput(encode("restic 0.17.3 compiled with go1.23")); got != "restic 0.17.3 compiled with go1.23" {
+		t.Fatalf("unexpected BOM-less decode: %q", got)
+	}
+	if got := backup.DecodeConsoleOutput([]byte("größe\r\nok")); got != "größe\nok" {
+		t.Fatalf("expected UTF-8 left intact, got %q", got)
+	}
+}
+
+func TestInteropExecutorUsesWSLPathAndReportsExitCode(t *testing.T) {
+	t.Parallel()
+
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	inner := &interopInnerExecutor{
+		responses: map[string]string{"wslpath -w /tmp/restore": `\\wsl.localhost\Ubuntu\tmp\restore`},
+		failure:   exitErr,
+	}
+	executor := backup.InteropExecutor{Executor: inner, WorkingDir: "/mnt/c", Environ: func() []string { return nil }}
+
+	_, err := executor.Run("restic.exe", "restore", "latest", "--target", "/tmp/restore")
+	if err == nil || !strings.Contains(err.Error(), "restic.exe exited with code 3") {
+		t.Fatalf("expected surfaced exit code, got %v", err)
+	}
+	if got := inner.calls[len(inner.calls)-1]; got != `restic.exe restore latest --target \\wsl.localhost\Ubuntu\tmp\restore` {
+		t.Fatalf("unexpected call: %q", got)
+	}
+	if inner.dir != "/mnt/c" {
+		t.Fatalf("expected windows working dir, got %q", inner.dir)
+	}
+}
+
+type interopInnerExecutor struct {
+	responses map[string]string
+	failure   error
+	calls     []string
+	dir       string
+}
+
+func (executor *interopInnerExecutor) Run(name string, args ...string) (string, error) {
+	return executor.RunWithOptions(backup.CommandOptions{}, name, args...)
+}
+
+func (executor *interopInnerExecutor) RunWithOptions(options backup.CommandOptions, name string, args ...string) (string, error) {
+	call := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	executor.calls = append(executor.calls, call)
+	if output, ok := executor.responses[call]; ok {
+		return output, nil
+	}
+	executor.dir = options.Dir
+	return "", executor.failure
+}
@@ -0,0 +1,169 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+func findingsContain(findings []backup.RuleFinding, severity backup.RuleSeverity, fragment string) bool {
+	for _, finding := range findings {
+		if finding.Severity == severity && strings.Contains(finding.Message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLintRulesReportsProfileFindings(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/me", "/home/me/projects", "/srv/data/cache"}},
+			ExcludeByCadence: backup.CadencePaths{Daily: []string{"/opt/elsewhere/*.tmp", "/srv/data", "**/node_modules", "!/home/me/keep"}},
+		},
+	}}
+
+	findings := backup.LintRules(plan, config)
+	if !findingsContain(findings, backup.SeverityWarning, "include /home/me/projects is already covered by include /home/me") {
+		t.Fatalf("expected redundant include warning, got %#v", findings)
+	}
+	if !findingsContain(findings, backup.SeverityWarning, "exclude /opt/elsewhere/*.tmp matches nothing under any include root") {
+		t.Fatalf("expected unreachable exclude warning, got %#v", findings)
+	}
+	if !findingsContain(findings, backup.SeverityError, "exclude /srv/data removes the entire include root /srv/data/cache") {
+		t.Fatalf("expected swallowed include error, got %#v", findings)
+	}
+	if findingsContain(findings, backup.SeverityWarning, "**/node_modules") {
+		t.Fatalf("relative patterns can match anywhere, got %#v", findings)
+	}
+}
+
+func TestLintRulesSwallowedRootWithGlobAndCaseInsensitiveWindowsPaths(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+
+	plan := backup.RunPlan{Cadence: "weekly", Targets: []string{"windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Weekly: []string{`C:\Users\Me\AppData\Local\Temp\work`}},
+			ExcludeByCadence: backup.CadencePaths{Weekly: []string{"**/AppData/Local/Temp"}},
+		},
+	}}
+
+	findings := backup.LintRules(plan, config)
+	if !findingsContain(findings, backup.SeverityError, `exclude **/AppData/Local/Temp removes the entire include root C:\Users\Me\AppData\Local\Temp\work`) {
+		t.Fatalf("expected swallowed include error, got %#v", findings)
+	}
+}
+
+func TestLintRulesAppliesNegatedExcludesInOrder(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/me/projects", "/home/me/photos", "/srv/cache", "/srv/logs"}},
+			ExcludeByCadence: backup.CadencePaths{Daily: []string{
+				"/home/me",
+				"!/home/me/projects",
+				"/home/me/photos",
+				"!/home/me/photos/keep",
+				"!/srv/logs",
+				"/srv",
+			}},
+		},
+	}}
+
+	findings := backup.LintRules(plan, config)
+	for _, reincluded := range []string{"/home/me/projects", "/home/me/photos"} {
+		if findingsContain(findings, backup.SeverityError, "entire include root "+reincluded) {
+			t.Fatalf("a later ! rule re-includes %s, got %#v", reincluded, findings)
+		}
+	}
+	for _, swallowed := range []string{"/srv/cache", "/srv/logs"} {
+		if !findingsContain(findings, backup.SeverityError, "exclude /srv removes the entire include root "+swallowed) {
+			t.Fatalf("expected %s swallowed by the last matching rule, got %#v", swallowed, findings)
+		}
+	}
+}
+
+func TestLintRulesReportsDuplicatesAcrossInlineAndRuleFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	rulesDir := filepath.Join(tempDir, "rules")
+	if err := os.MkdirAll(rulesDir, 0o755); err != nil {
+		t.Fatalf("mkdir rules dir: %v", err)
+	}
+	ruleFile := filepath.Join(rulesDir, "wsl.exclude.daily.txt")
+	if err := os.WriteFile(ruleFile, []byte("/home/me/.cache\n"), 0o644); err != nil {
+		t.Fatalf("write rule file: %v", err)
+	}
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("profiles:\n  wsl:\n    repository: /repo\n    include:\n      - /home/me\n    exclude:\n      - /home/me/.cache\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	findings := backup.LintRules(backup.RunPlan{Cadence: "daily", Targets: []string{"wsl"}}, config)
+	expected := "duplicate exclude /home/me/.cache (inline " + configPath + "; file " + ruleFile + ")"
+	if !findingsContain(findings, backup.SeverityInfo, expected) {
+		t.Fatalf("expected duplicate finding %q, got %#v", expected, findings)
+	}
+}
+
+func TestLintRulesIncludesCrossPlatformOverlapsAsErrors(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl":     {IncludeByCadence: backup.CadencePaths{Daily: []string{"/mnt/c/Users/me"}}},
+		"windows": {IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\me\Documents`}}},
+	}}
+
+	findings := backup.LintRules(plan, config)
+	if !findingsContain(findings, backup.SeverityError, `include wsl=/mnt/c/Users/me overlaps windows=C:\Users\me\Documents`) {
+		t.Fatalf("expected overlap error, got %#v", findings)
+	}
+	if !strings.HasPrefix(backup.FormatRuleFindings(findings), "[error] wsl,windows:") {
+		t.Fatalf("expected errors first, got %q", backup.FormatRuleFindings(findings))
+	}
+}
+
+func TestLintRulesOverlapSeverityFollowsPolicy(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	overlap := `include wsl=/mnt/c/Users/me overlaps windows=C:\Users\me\Documents`
+	cases := map[string]struct {
+		severity backup.RuleSeverity
+		message  string
+	}{
+		backup.OverlapPolicyWarn: {backup.SeverityWarning, overlap},
+		backup.OverlapPolicyOff:  {backup.SeverityInfo, "accepted overlap (overlap_policy off): " + overlap},
+	}
+	for policy, expected := range cases {
+		config := backup.AppConfig{OverlapPolicy: policy, Profiles: map[string]backup.ProfileConfig{
+			"wsl":     {IncludeByCadence: backup.CadencePaths{Daily: []string{"/mnt/c/Users/me"}}},
+			"windows": {IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\me\Documents`}}},
+		}}
+		findings := backup.LintRules(plan, config)
+		if !findingsContain(findings, expected.severity, expected.message) {
+			t.Fatalf("policy %s: expected %s %q, got %#v", policy, expected.severity, expected.message, findings)
+		}
+	}
+}
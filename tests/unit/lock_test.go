@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+func TestAcquireInstanceLockReportsHolder(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	backup.SetClockForTests(func() time.Time { return time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC) })
+	t.Cleanup(func() { backup.SetClockForTests(nil) })
+
+	lock, err := backup.AcquireInstanceLock("run monthly", false)
+	if err != nil {
+		t.Fatalf("AcquireInstanceLock returned error: %v", err)
+	}
+	defer lock.Release()
+
+	if _, err := os.Stat(filepath.Join(stateDir, "backup.lock")); err != nil {
+		t.Fatalf("expected lock file in state dir: %v", err)
+	}
+
+	_, err = backup.AcquireInstanceLock("run daily", false)
+	if err == nil {
+		t.Fatal("expected second acquisition to fail")
+	}
+	expected := fmt.Sprintf("another backup is running since 2026-03-10T02:00:00Z (pid %d, run monthly); use --wait to queue behind it", os.Getpid())
+	if err.Error() != expected {
+		t.Fatalf("unexpected error: %q", err.Error())
+	}
+}
+
+func TestAcquireInstanceLockWaitQueuesUntilReleased(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+
+	first, err := backup.AcquireInstanceLock("run monthly", false)
+	if err != nil {
+		t.Fatalf("AcquireInstanceLock returned error: %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Release()
+	}()
+
+	second, err := backup.AcquireInstanceLock("run daily", true)
+	if err != nil {
+		t.Fatalf("waiting acquisition returned error: %v", err)
+	}
+	second.Release()
+}
+
+func TestRunFailsWhileAnotherBackupHoldsLock(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+	setupOverlapRun(t, "overlap_policy: warn\n")
+
+	lock, err := backup.AcquireInstanceLock("restore /tmp/restore", false)
+	if err != nil {
+		t.Fatalf("AcquireInstanceLock returned error: %v", err)
+	}
+	defer lock.Release()
+
+	executor := &fakeExecutor{}
+	_, err = backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor)
+	if err == nil || !strings.Contains(err.Error(), "another backup is running since") {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+	if len(executor.calls) != 0 {
+		t.Fatalf("expected no restic calls, got %#v", executor.calls)
+	}
+
+	command, err := backup.ParseArgs([]string{"restore", "/tmp/restore", "--wait"})
+	if err != nil || !command.Wait {
+		t.Fatalf("unexpected restore parse: %#v, %v", command, err)
+	}
+}
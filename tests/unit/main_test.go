@@ -0,0 +1,28 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+// Runs append to the run history and take the instance lock; keep both out
+// of the developer's real state directory, and keep a host
+// /etc/backup/config.yaml out of every config layer. The snapshot host
+// defaults to the machine name, so pin it for stable restic arguments.
+func TestMain(m *testing.M) {
+	stateDir, err := os.MkdirTemp("", "backup-unit-state-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("BACKUP_STATE_DIR", stateDir)
+	_ = os.Setenv("BACKUP_SYSTEM_CONFIG", filepath.Join(stateDir, "no-system-config.yaml"))
+	backup.SetHostnameForTests(func() (string, error) { return "TestHost.example", nil })
+	code := m.Run()
+	_ = os.RemoveAll(stateDir)
+	os.Exit(code)
+}
@@ -0,0 +1,139 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+const resticBackupJSONOutput = `{"message_type":"status","percent_done":0.5,"total_files":10}
+{"message_type":"summary","files_new":3,"files_changed":2,"files_unmodified":40,"data_added":4096,"total_duration":1.5,"snapshot_id":"1a2b3c4d"}`
+
+func TestParseBackupSummaryReadsFinalSummaryLine(t *testing.T) {
+	t.Parallel()
+
+	summary, ok := backup.ParseBackupSummary("warning: something on stderr\n" + resticBackupJSONOutput)
+	if !ok {
+		t.Fatal("expected summary")
+	}
+	if summary.FilesNew != 3 || summary.FilesChanged != 2 || summary.DataAdded != 4096 || summary.SnapshotID != "1a2b3c4d" {
+		t.Fatalf("unexpected summary: %#v", summary)
+	}
+	if _, ok := backup.ParseBackupSummary("ok"); ok {
+		t.Fatal("plain output must not parse as summary")
+	}
+}
+
+func TestFormatPrometheusMetricsUsesLatestRunPerSeries(t *testing.T) {
+	t.Parallel()
+
+	base := time.Unix(1_700_000_000, 0).UTC()
+	records := []backup.RunRecord{
+		{Cadence: "daily", Profile: "wsl", FinishedAt: base, Success: true, Duration: 12.5, DataAdded: 100, FilesNew: 1, FilesChanged: 2},
+		{Cadence: "daily", Profile: "wsl", FinishedAt: base.Add(time.Hour), Success: false, ExitCode: 3, Duration: 4},
+		{Cadence: "weekly", Profile: "windows", FinishedAt: base.Add(2 * time.Hour), Success: false, ExitCode: 1},
+	}
+
+	output := backup.FormatPrometheusMetrics(records)
+	for _, expected := range []string{
+		"# TYPE backup_last_success_timestamp_seconds gauge\n",
+		`backup_last_success_timestamp_seconds{profile="wsl",cadence="daily"} 1700000000` + "\n",
+		`backup_last_run_timestamp_seconds{profile="wsl",cadence="daily"} 1700003600` + "\n",
+		`backup_last_run_success{profile="wsl",cadence="daily"} 0` + "\n",
+		`backup_last_run_exit_status{profile="wsl",cadence="daily"} 3` + "\n",
+		`backup_last_run_duration_seconds{profile="wsl",cadence="daily"} 4` + "\n",
+		`backup_last_run_exit_status{profile="windows",cadence="weekly"} 1` + "\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in metrics:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, `backup_last_success_timestamp_seconds{profile="windows"`) {
+		t.Fatalf("series without a success must not report a success timestamp:\n%s", output)
+	}
+}
+
+type summaryExecutor struct {
+	versionExecutor
+}
+
+func (executor *summaryExecutor) Run(name string, args ...string) (string, error) {
+	if len(args) > 2 && args[2] == "backup" {
+		return resticBackupJSONOutput, nil
+	}
+	return executor.versionExecutor.Run(name, args...)
+}
+
+func TestRunWritesMetricsTextfile(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+	textfile := filepath.Join(t.TempDir(), "collector", "backup.prom")
+	setupOverlapRun(t, "overlap_policy: \"off\"\nmetrics:\n  textfile: "+textfile+"\n")
+
+	executor := &summaryExecutor{versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": backup.PinnedResticVersion}}}
+	if _, err := backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("read metrics textfile: %v", err)
+	}
+	for _, expected := range []string{
+		`backup_last_run_data_added_bytes{profile="windows",cadence="daily"} 4096`,
+		`backup_last_run_files_changed{profile="wsl",cadence="daily"} 2`,
+		`backup_last_run_success{profile="wsl",cadence="daily"} 1`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("expected %q in textfile:\n%s", expected, content)
+		}
+	}
+
+	output, err := backup.Run(backup.Command{Name: "metrics"}, executor)
+	if err != nil {
+		t.Fatalf("metrics returned error: %v", err)
+	}
+	if output+"\n" != string(content) {
+		t.Fatalf("backup metrics should print the textfile content\n--- metrics\n%s\n--- textfile\n%s", output, content)
+	}
+}
+
+func TestRunRecordsFailedPreflightInHistoryAndMetrics(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+	textfile := filepath.Join(t.TempDir(), "backup.prom")
+	setupOverlapRun(t, "overlap_policy: \"off\"\nmetrics:\n  textfile: "+textfile+"\n")
+
+	executor := &versionExecutor{versions: map[string]string{"restic": backup.PinnedResticVersion, "restic.exe": "0.16.4"}}
+	if _, err := backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor); err == nil || !strings.Contains(err.Error(), "restic preflight failed") {
+		t.Fatalf("expected preflight failure, got %v", err)
+	}
+
+	records, err := backup.LoadRunHistory()
+	if err != nil {
+		t.Fatalf("LoadRunHistory returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a failed record per target, got %#v", records)
+	}
+	for _, record := range records {
+		if record.Success || !strings.Contains(record.Error, "restic version mismatch") {
+			t.Fatalf("expected failed record carrying the preflight error, got %#v", record)
+		}
+	}
+
+	content, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("read metrics textfile: %v", err)
+	}
+	for _, expected := range []string{
+		`backup_last_run_success{profile="windows",cadence="daily"} 0`,
+		`backup_last_run_success{profile="wsl",cadence="daily"} 0`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("expected %q in textfile:\n%s", expected, content)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+const snapshotsJSON = `[
+  {"id":"aaaa1111","short_id":"aaaa1111","time":"2026-03-01T04:00:00Z","hostname":"workstation","tags":["cadence:monthly","profile:wsl"],"paths":["/home/me"]},
+  {"id":"bbbb2222","short_id":"bbbb2222","time":"2026-03-08T12:00:00Z","hostname":"workstation","tags":["cadence:weekly","profile:wsl"],"paths":["/home/me"]},
+  {"id":"cccc3333","short_id":"cccc3333","time":"2026-03-09T12:00:00Z","hostname":"workstation","tags":["cadence:daily","profile:wsl"],"paths":["/home/me"]}
+]`
+
+func TestSelectSnapshotFiltersByCadenceAndDate(t *testing.T) {
+	t.Parallel()
+
+	executor := &scriptedExecutor{responses: map[string]string{"restic -r /repo snapshots --json": snapshotsJSON}}
+	snapshots, err := backup.ListSnapshots("restic", "/repo", nil, executor)
+	if err != nil {
+		t.Fatalf("ListSnapshots returned error: %v", err)
+	}
+
+	cases := []struct {
+		cadence  string
+		date     string
+		expected string
+	}{
+		{"", "", "cccc3333"},
+		{"weekly", "", "bbbb2222"},
+		{"", "2026-03-07", "aaaa1111"},
+		{"daily", "2026-03-08", ""},
+	}
+	for _, testCase := range cases {
+		snapshot, found, err := backup.SelectSnapshot(snapshots, testCase.cadence, testCase.date)
+		if err != nil {
+			t.Fatalf("SelectSnapshot returned error: %v", err)
+		}
+		if testCase.expected == "" {
+			if found {
+				t.Fatalf("expected no match for %#v, got %s", testCase, snapshot.ShortID)
+			}
+			continue
+		}
+		if !found || snapshot.ShortID != testCase.expected {
+			t.Fatalf("expected %s for %#v, got %s", testCase.expected, testCase, snapshot.ShortID)
+		}
+	}
+	if _, _, err := backup.SelectSnapshot(snapshots, "", "March 1"); err == nil {
+		t.Fatal("expected invalid date error")
+	}
+}
+
+func TestRunMountPrintsSnapshotPathAndRunsResticMount(t *testing.T) {
+	var mountCall string
+	backup.SetForegroundRunnerForTests(func(name string, args ...string) error {
+		mountCall = name + " " + strings.Join(args, " ")
+		return nil
+	})
+	t.Cleanup(func() { backup.SetForegroundRunnerForTests(nil) })
+
+	mountPoint := filepath.Join(t.TempDir(), "mnt")
+	config := backup.AppConfig{Exists: true, ResticMinVersion: "0.16.0", Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic version":                   "restic 0.17.3 compiled with go1.23 on linux/amd64",
+		"restic -r /repo snapshots --json": snapshotsJSON,
+	}}
+	var progress bytes.Buffer
+
+	output, err := backup.RunMount(backup.Command{Name: "mount", Target: mountPoint, Cadence: "weekly"}, config, executor, &progress)
+	if err != nil {
+		t.Fatalf("RunMount returned error: %v", err)
+	}
+	if mountCall != "restic -r /repo mount "+mountPoint {
+		t.Fatalf("unexpected mount call: %q", mountCall)
+	}
+	if !strings.Contains(progress.String(), "snapshot bbbb2222 (") || !strings.Contains(progress.String(), "weekly): "+filepath.Join(mountPoint, "ids", "bbbb2222")) {
+		t.Fatalf("unexpected progress: %q", progress.String())
+	}
+	if output != "unmounted "+mountPoint {
+		t.Fatalf("unexpected output: %q", output)
+	}
+}
+
+func TestRunMountFailsWithoutMatchingSnapshot(t *testing.T) {
+	backup.SetForegroundRunnerForTests(func(string, ...string) error {
+		t.Fatal("restic mount must not start without a matching snapshot")
+		return nil
+	})
+	t.Cleanup(func() { backup.SetForegroundRunnerForTests(nil) })
+
+	config := backup.AppConfig{Exists: true, ResticMinVersion: "0.16.0", Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	executor := &scriptedExecutor{responses: map[string]string{
+		"restic version":                   "restic 0.17.3 compiled with go1.23 on linux/amd64",
+		"restic -r /repo snapshots --json": snapshotsJSON,
+	}}
+
+	_, err := backup.RunMount(backup.Command{Name: "mount", Target: t.TempDir(), Cadence: "daily", Date: "2026-03-08"}, config, executor, &bytes.Buffer{})
+	if err == nil || err.Error() != "no wsl snapshot matches cadence daily on or before 2026-03-08" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseArgsMount(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"mount", "windows", "/mnt/snapshots", "--cadence=monthly", "--date=2026-03-01"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Profile != "windows" || command.Target != "/mnt/snapshots" || command.Cadence != "monthly" || command.Date != "2026-03-01" {
+		t.Fatalf("unexpected command: %#v", command)
+	}
+	if _, err := backup.ParseArgs([]string{"mount"}); err == nil {
+		t.Fatal("expected missing mountpoint error")
+	}
+}
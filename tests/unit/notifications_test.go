@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+type webhookRecorder struct {
+	server   *httptest.Server
+	payloads []backup.RunSummary
+	headers  []http.Header
+}
+
+func newWebhookRecorder(t *testing.T, status int) *webhookRecorder {
+	t.Helper()
+	recorder := &webhookRecorder{}
+	recorder.server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var payload backup.RunSummary
+		if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+			t.Errorf("decode webhook payload: %v", err)
+		}
+		recorder.payloads = append(recorder.payloads, payload)
+		recorder.headers = append(recorder.headers, request.Header.Clone())
+		writer.WriteHeader(status)
+	}))
+	t.Cleanup(recorder.server.Close)
+	return recorder
+}
+
+func TestSendRunNotificationsPostsWebhookPayload(t *testing.T) {
+	t.Setenv("BACKUP_WEBHOOK_TOKEN", "s3cret")
+	recorder := newWebhookRecorder(t, http.StatusNoContent)
+
+	startedAt := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	summary := backup.BuildRunSummary("daily", startedAt, startedAt.Add(time.Minute), []backup.ExecutionResult{
+		{Target: "wsl", Duration: 30 * time.Second},
+		{Target: "windows", Err: os.ErrPermission, Duration: 5 * time.Second},
+	}, nil)
+	config := backup.NotificationConfig{On: "failure", Webhook: backup.WebhookConfig{
+		URL:     recorder.server.URL,
+		On:      "failure",
+		Headers: map[string]string{"Authorization": "Bearer ${BACKUP_WEBHOOK_TOKEN}"},
+		Timeout: time.Second,
+	}}
+
+	warnings := backup.SendRunNotifications(config, summary, &scriptedExecutor{})
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+	if len(recorder.payloads) != 1 {
+		t.Fatalf("expected one webhook call, got %d", len(recorder.payloads))
+	}
+	payload := recorder.payloads[0]
+	if payload.Status != "failure" || payload.Cadence != "daily" || len(payload.Targets) != 2 {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+	if payload.Targets[1].Status != "failure" || payload.Targets[1].Error == "" || payload.Targets[0].DurationSeconds != 30 {
+		t.Fatalf("unexpected target results: %#v", payload.Targets)
+	}
+	if got := recorder.headers[0].Get("Authorization"); got != "Bearer s3cret" {
+		t.Fatalf("unexpected authorization header: %q", got)
+	}
+}
+
+func TestSendRunNotificationsHonorsTriggers(t *testing.T) {
+	recorder := newWebhookRecorder(t, http.StatusOK)
+	summary := backup.BuildRunSummary("weekly", time.Now(), time.Now(), []backup.ExecutionResult{{Target: "wsl"}}, []string{"warning: platform include overlap detected: x"})
+	if summary.Status != "warning" {
+		t.Fatalf("expected warning status, got %q", summary.Status)
+	}
+
+	executor := &scriptedExecutor{}
+	config := backup.NotificationConfig{On: "warning", NotifySend: true, Toast: true, Webhook: backup.WebhookConfig{URL: recorder.server.URL, On: "failure", Timeout: time.Second}}
+	if warnings := backup.SendRunNotifications(config, summary, executor); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+	if len(recorder.payloads) != 0 {
+		t.Fatalf("failure-only webhook should not fire for warnings")
+	}
+	if len(executor.calls) != 2 {
+		t.Fatalf("expected toast and notify-send calls, got %#v", executor.calls)
+	}
+	if !strings.HasPrefix(executor.calls[0], "powershell.exe -NoProfile -NonInteractive -Command ") || !strings.Contains(executor.calls[0], "'backup weekly run completed with warnings'") {
+		t.Fatalf("unexpected toast call: %q", executor.calls[0])
+	}
+	if !strings.HasPrefix(executor.calls[1], "notify-send --app-name=backup --urgency=normal backup weekly run completed with warnings") {
+		t.Fatalf("unexpected notify-send call: %q", executor.calls[1])
+	}
+
+	success := backup.BuildRunSummary("weekly", time.Now(), time.Now(), []backup.ExecutionResult{{Target: "wsl"}}, nil)
+	executor = &scriptedExecutor{}
+	backup.SendRunNotifications(config, success, executor)
+	if len(executor.calls) != 0 {
+		t.Fatalf("successful run should not notify on warning trigger, got %#v", executor.calls)
+	}
+}
+
+func TestSendRunNotificationsReportsWebhookFailure(t *testing.T) {
+	recorder := newWebhookRecorder(t, http.StatusInternalServerError)
+	summary := backup.BuildRunSummary("daily", time.Now(), time.Now(), nil, nil)
+
+	warnings := backup.SendRunNotifications(backup.NotificationConfig{Webhook: backup.WebhookConfig{URL: recorder.server.URL, On: "always", Timeout: time.Second}}, summary, &scriptedExecutor{})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "webhook notification failed: unexpected status 500") {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+}
+
+func TestLoadConfigReadsNotifications(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	content := []byte("notifications:\n  on: warning\n  toast: true\n  webhook:\n    url: https://hooks.example.test/backup\n    on: always\n    timeout: 3s\n")
+	if err := os.WriteFile(configPath, content, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("BACKUP_CONFIG", configPath)
+
+	config, err := backup.LoadConfig(backup.RuntimeWSL)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	notifications := config.Notifications
+	if notifications.On != "warning" || !notifications.Toast || notifications.NotifySend {
+		t.Fatalf("unexpected notifications: %#v", notifications)
+	}
+	if notifications.Webhook.On != "always" || notifications.Webhook.Timeout != 3*time.Second {
+		t.Fatalf("unexpected webhook config: %#v", notifications.Webhook)
+	}
+
+	if err := os.WriteFile(configPath, []byte("notifications:\n  on: sometimes\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := backup.LoadConfig(backup.RuntimeWSL); err == nil || !strings.Contains(err.Error(), `invalid notification trigger "sometimes"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunNotifiesWebhookOnFailedInvocation(t *testing.T) {
+	recorder := newWebhookRecorder(t, http.StatusOK)
+	setupOverlapRun(t, "overlap_policy: \"off\"\nnotifications:\n  webhook:\n    url: "+recorder.server.URL+"\n")
+
+	executor := &scriptedExecutor{
+		responses: map[string]string{
+			"restic version":     "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on linux/amd64",
+			"restic.exe version": "restic " + backup.PinnedResticVersion + " compiled with go1.23.3 on windows/amd64",
+		},
+		failures: map[string]string{
+			`restic.exe -r C:\repo\windows backup --json --tag cadence:daily --tag profile:windows --tag backup-cli:dev C:\Users\me`: "repository is locked",
+		},
+	}
+	_, err := backup.Run(backup.Command{Name: "run", Cadence: "daily"}, executor)
+	if err == nil || !strings.Contains(err.Error(), "windows invocation failed") {
+		t.Fatalf("expected windows failure, got %v", err)
+	}
+	if len(recorder.payloads) != 1 || recorder.payloads[0].Status != "failure" {
+		t.Fatalf("expected failure webhook, got %#v", recorder.payloads)
+	}
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+const testProcMounts = `/dev/sdc / ext4 rw,relatime,discard,errors=remount-ro,data=ordered 0 0
+C:\134 /win/c 9p rw,noatime,dirsync,aname=drvfs;path=C:\;uid=1000;gid=1000;symlinkroot=/win/ 0 0
+D:\134data /data drvfs rw,noatime,uid=1000,gid=1000 0 0
+tmpfs /run tmpfs rw,nosuid,nodev 0 0
+`
+
+func newTestTranslator() backup.PathTranslator {
+	return backup.NewPathTranslator("[automount]\nroot = /win/\noptions = \"metadata\"\n", testProcMounts, "Ubuntu")
+}
+
+func TestParseWSLConfAutomountRoot(t *testing.T) {
+	t.Parallel()
+
+	if root := backup.ParseWSLConfAutomountRoot(""); root != "/mnt/" {
+		t.Fatalf("expected default root, got %q", root)
+	}
+	if root := backup.ParseWSLConfAutomountRoot("[boot]\nsystemd=true\n[automount]\nroot = \"/win\" # custom\n"); root != "/win/" {
+		t.Fatalf("unexpected root: %q", root)
+	}
+}
+
+func TestParseProcMountsDecodesDrvfsRoots(t *testing.T) {
+	t.Parallel()
+
+	mounts := backup.ParseProcMounts(testProcMounts)
+	if len(mounts) != 4 {
+		t.Fatalf("unexpected mounts: %#v", mounts)
+	}
+	if mounts[1].WindowsRoot() != `C:\` || mounts[1].MountPoint != "/win/c" {
+		t.Fatalf("unexpected 9p drvfs mount: %#v", mounts[1])
+	}
+	if mounts[2].WindowsRoot() != `D:\data` {
+		t.Fatalf("unexpected drvfs mount: %#v", mounts[2])
+	}
+	if mounts[0].WindowsRoot() != "" {
+		t.Fatalf("expected linux mount, got %#v", mounts[0])
+	}
+}
+
+func TestPathTranslatorToWSL(t *testing.T) {
+	t.Parallel()
+
+	translator := newTestTranslator()
+	cases := []struct {
+		raw           string
+		path          string
+		key           string
+		windowsBacked bool
+	}{
+		{raw: `C:\Users\Me\Projects`, path: "/win/c/Users/Me/Projects", key: "/win/c/users/me/projects", windowsBacked: true},
+		{raw: "/win/c/Users/Me", path: "/win/c/Users/Me", key: "/win/c/users/me", windowsBacked: true},
+		{raw: `D:\data\Photos`, path: "/data/Photos", key: "/data/photos", windowsBacked: true},
+		{raw: `E:\Archive`, path: "/win/e/Archive", key: "/win/e/archive", windowsBacked: true},
+		{raw: `\\wsl$\Ubuntu\home\Me`, path: "/home/Me", key: "/home/Me", windowsBacked: false},
+		{raw: `\\wsl.localhost\ubuntu\home\Me\docs`, path: "/home/Me/docs", key: "/home/Me/docs", windowsBacked: false},
+		{raw: `\\wsl.localhost\Debian\home\Me`, path: "//wsl/Debian/home/Me", key: "//wsl/debian/home/Me", windowsBacked: false},
+		{raw: "/home/Me/", path: "/home/Me", key: "/home/Me", windowsBacked: false},
+	}
+
+	for _, testCase := range cases {
+		translated, ok := translator.ToWSL(testCase.raw)
+		if !ok {
+			t.Fatalf("expected %q to translate", testCase.raw)
+		}
+		if translated.Path != testCase.path || translated.Key != testCase.key || translated.WindowsBacked != testCase.windowsBacked {
+			t.Fatalf("unexpected translation for %q: %#v", testCase.raw, translated)
+		}
+	}
+
+	if _, ok := translator.ToWSL("relative/path"); ok {
+		t.Fatal("expected relative path to be rejected")
+	}
+}
+
+func TestPathTranslatorKeepsLinuxCaseSensitivity(t *testing.T) {
+	t.Parallel()
+
+	translator := newTestTranslator()
+	upper, _ := translator.ToWSL("/home/Me")
+	lower, _ := translator.ToWSL("/home/me")
+	if backup.TranslatedPathsOverlap(upper, lower) {
+		t.Fatal("linux paths differing in case must not overlap")
+	}
+
+	windowsUpper, _ := translator.ToWSL(`C:\Users\ME`)
+	windowsLower, _ := translator.ToWSL("/win/c/users/me/docs")
+	if !backup.TranslatedPathsOverlap(windowsUpper, windowsLower) {
+		t.Fatal("windows-backed paths must compare case-insensitively")
+	}
+}
+
+func TestPathTranslatorResolvesSymlinks(t *testing.T) {
+	t.Parallel()
+
+	translator := newTestTranslator()
+	translator.ResolveSymlinks = func(path string) (string, error) {
+		if path == "/home/Me/winhome" {
+			return "/win/c/Users/Me", nil
+		}
+		return path, nil
+	}
+
+	translated, ok := translator.ToWSL("/home/Me/winhome")
+	if !ok || !translated.WindowsBacked || translated.Key != "/win/c/users/me" {
+		t.Fatalf("unexpected symlink translation: %#v", translated)
+	}
+}
+
+func TestPathTranslatorToWindows(t *testing.T) {
+	t.Parallel()
+
+	translator := newTestTranslator()
+	cases := map[string]string{
+		"/win/c/Users/Me": `C:\Users\Me`,
+		"/win/c":          `C:\`,
+		"/data/Photos":    `D:\data\Photos`,
+		"/win/e/Archive":  `E:\Archive`,
+		"/home/Me/docs":   `\\wsl.localhost\Ubuntu\home\Me\docs`,
+		`C:\Already`:      `C:\Already`,
+	}
+	for raw, expected := range cases {
+		translated, ok := translator.ToWindows(raw)
+		if !ok || translated != expected {
+			t.Fatalf("unexpected windows translation for %q: %q", raw, translated)
+		}
+	}
+}
+
+func TestFindPlatformIncludeOverlapWarningsUsesTranslator(t *testing.T) {
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	backup.SetPathTranslatorForTests(newTestTranslator)
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/Me", "/win/c/Users/Me/Projects"}},
+		},
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\users\me`, `\\wsl.localhost\Ubuntu\home\Me\docs`, `\\wsl$\Ubuntu\home\me`}},
+		},
+	}}
+
+	joined := strings.Join(backup.FindPlatformIncludeOverlapWarnings(plan, config), "\n")
+	if !strings.Contains(joined, `wsl=/win/c/Users/Me/Projects overlaps windows=C:\users\me`) {
+		t.Fatalf("expected drvfs overlap, got %q", joined)
+	}
+	if !strings.Contains(joined, `wsl=/home/Me overlaps windows=\\wsl.localhost\Ubuntu\home\Me\docs`) {
+		t.Fatalf("expected wsl UNC overlap, got %q", joined)
+	}
+	if strings.Contains(joined, `\\wsl$\Ubuntu\home\me`) {
+		t.Fatalf("case-different linux path must not overlap, got %q", joined)
+	}
+}
@@ -109,3 +109,102 @@ func TestFindPlatformIncludeOverlapWarningsNoOverlap(t *testing.T) {
 		t.Fatalf("expected no warnings, got %#v", warnings)
 	}
 }
+
+func TestFindPlatformIncludeOverlapsExpandsVariablesAndReportsUncheckable(t *testing.T) {
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	t.Setenv("HOME", "/mnt/c/Users/me")
+	t.Setenv("USERPROFILE", `C:\Users\me`)
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"wsl":     {IncludeByCadence: backup.CadencePaths{Daily: []string{"$HOME/Projects"}}},
+		"windows": {IncludeByCadence: backup.CadencePaths{Daily: []string{`%USERPROFILE%\Projects`, `%BACKUP_UNSET_DIR%\Music`, "$BACKUP_UNSET_DIR/Videos"}}},
+	}}
+
+	overlaps := backup.FindPlatformIncludeOverlaps(plan, config)
+	if len(overlaps) != 1 || overlaps[0].LeftPath != "$HOME/Projects" || overlaps[0].RightPath != `%USERPROFILE%\Projects` {
+		t.Fatalf("expected the expanded includes to overlap, got %#v", overlaps)
+	}
+	uncheckable := backup.FindUncheckableIncludes(plan, config)
+	if len(uncheckable) != 2 || uncheckable[0].Path != `%BACKUP_UNSET_DIR%\Music` || uncheckable[1].Path != "$BACKUP_UNSET_DIR/Videos" {
+		t.Fatalf("unexpected uncheckable includes: %#v", uncheckable)
+	}
+
+	config.OverlapPolicy = backup.OverlapPolicyDedupe
+	assignments, err := backup.PlanOverlapOwnership(plan, config)
+	if err != nil || len(assignments) != 1 || assignments[0].Owner != "windows" || assignments[0].DroppedInclude != "$HOME/Projects" {
+		t.Fatalf("unexpected dedupe ownership: %#v, %v", assignments, err)
+	}
+	findings := backup.FormatRuleFindings(backup.LintRules(plan, config))
+	if !strings.Contains(findings, `[warning] windows: uncheckable include %BACKUP_UNSET_DIR%\Music`) {
+		t.Fatalf("expected uncheckable include in lint findings:\n%s", findings)
+	}
+}
+
+func dedupeTestConfig(winner string) backup.AppConfig {
+	return backup.AppConfig{OverlapPolicy: "dedupe", OverlapWinner: winner, Profiles: map[string]backup.ProfileConfig{
+		"wsl": {
+			RepositoryHint:   "/repo/wsl",
+			IncludeByCadence: backup.CadencePaths{Daily: []string{"/home/me", "/mnt/c/Users/me/Projects"}},
+		},
+		"windows": {
+			RepositoryHint:   `C:\repo`,
+			IncludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\me`}},
+		},
+	}}
+}
+
+func TestPlanOverlapOwnershipAutoGivesWindowsBackedPathsToWindows(t *testing.T) {
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := dedupeTestConfig("")
+	assignments, err := backup.PlanOverlapOwnership(plan, config)
+	if err != nil {
+		t.Fatalf("PlanOverlapOwnership returned error: %v", err)
+	}
+	if len(assignments) != 1 {
+		t.Fatalf("unexpected assignments: %#v", assignments)
+	}
+	if assignments[0].Owner != "windows" || assignments[0].Loser != "wsl" || assignments[0].DroppedInclude != "/mnt/c/Users/me/Projects" {
+		t.Fatalf("unexpected assignment: %#v", assignments[0])
+	}
+
+	plan.Ownership = assignments
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	if got := strings.Join(invocations[0].Args, " "); got != "-r /repo/wsl backup --json --tag cadence:daily --tag profile:wsl --tag backup-cli:dev /home/me" {
+		t.Fatalf("unexpected wsl args: %q", got)
+	}
+	if got := strings.Join(invocations[1].Args, " "); got != `-r C:\repo backup --json --tag cadence:daily --tag profile:windows --tag backup-cli:dev C:\Users\me` {
+		t.Fatalf("unexpected windows args: %q", got)
+	}
+}
+
+func TestPlanOverlapOwnershipConfiguredWinnerExcludesFromOtherProfile(t *testing.T) {
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	plan := backup.RunPlan{Cadence: "daily", Targets: []string{"wsl", "windows"}}
+	config := dedupeTestConfig("wsl")
+	assignments, err := backup.PlanOverlapOwnership(plan, config)
+	if err != nil {
+		t.Fatalf("PlanOverlapOwnership returned error: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].Owner != "wsl" || assignments[0].Exclude != `C:\Users\me\Projects` {
+		t.Fatalf("unexpected assignments: %#v", assignments)
+	}
+
+	plan.Ownership = assignments
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	if got := strings.Join(invocations[1].Args, " "); got != `-r C:\repo backup --json --tag cadence:daily --tag profile:windows --tag backup-cli:dev --exclude C:\Users\me\Projects C:\Users\me` {
+		t.Fatalf("unexpected windows args: %q", got)
+	}
+}
@@ -0,0 +1,225 @@
+package unit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	backup "wsl-backup-cli/src"
+)
+
+// stagingRestoreExecutor answers like scriptedExecutor and, for restic
+// restore, writes the files listed in --include-file into the --target dir.
+type stagingRestoreExecutor struct {
+	scriptedExecutor
+	contents map[string]string
+	mtime    time.Time
+	includes []string
+}
+
+func (executor *stagingRestoreExecutor) Run(name string, args ...string) (string, error) {
+	output, err := executor.scriptedExecutor.Run(name, args...)
+	if len(args) < 3 || args[2] != "restore" {
+		return output, err
+	}
+	target := ""
+	for index := 0; index+1 < len(args); index++ {
+		switch args[index] {
+		case "--target":
+			target = args[index+1]
+		case "--include-file":
+			content, err := os.ReadFile(args[index+1])
+			if err != nil {
+				return "", err
+			}
+			executor.includes = strings.Fields(string(content))
+		}
+	}
+	for _, include := range executor.includes {
+		staged := filepath.Join(target, include)
+		if err := os.MkdirAll(filepath.Dir(staged), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(staged, []byte(executor.contents[include]), 0o644); err != nil {
+			return "", err
+		}
+		if err := os.Chtimes(staged, executor.mtime, executor.mtime); err != nil {
+			return "", err
+		}
+	}
+	return output, err
+}
+
+func TestRunInPlaceRestorePreviewsThenAppliesConflictPolicy(t *testing.T) {
+	root := t.TempDir()
+	snapshotTime := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	write := func(name string, content string, mtime time.Time) {
+		localPath := filepath.Join(root, name)
+		if err := os.WriteFile(localPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := os.Chtimes(localPath, mtime, mtime); err != nil {
+			t.Fatalf("chtimes %s: %v", name, err)
+		}
+	}
+	write("older.txt", "stale", snapshotTime.Add(-time.Hour))
+	write("newer.txt", "edited later", snapshotTime.Add(time.Hour))
+	write("same.txt", "same", snapshotTime)
+
+	node := func(name string, size int) string {
+		return fmt.Sprintf(`{"struct_type":"node","type":"file","path":%q,"size":%d,"mtime":%q}`, root+"/"+name, size, snapshotTime.Format(time.RFC3339))
+	}
+	executor := &stagingRestoreExecutor{
+		scriptedExecutor: scriptedExecutor{responses: map[string]string{
+			"restic version":                   "restic 0.17.3 compiled with go1.23 on linux/amd64",
+			"restic -r /repo snapshots --json": fmt.Sprintf(`[{"id":"eeee5555","short_id":"eeee5555","time":"2026-03-09T12:00:00Z","tags":["cadence:daily"],"paths":[%q]}]`, root),
+			"restic -r /repo ls --json eeee5555": strings.Join([]string{
+				node("missing.txt", 7), node("older.txt", 8), node("newer.txt", 8), node("same.txt", 4),
+			}, "\n"),
+			"restic -r /repo backup --json --tag pre-restore --tag profile:wsl --tag backup-cli:dev " + filepath.Join(root, "older.txt"): `{"message_type":"summary","snapshot_id":"ffff6666"}`,
+		}},
+		contents: map[string]string{root + "/missing.txt": "missing", root + "/older.txt": "restored"},
+		mtime:    snapshotTime,
+	}
+	config := backup.AppConfig{Exists: true, ResticMinVersion: "0.16.0", Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	command := backup.Command{Name: "restore", InPlace: true, Conflict: backup.ConflictOverwriteIfNewer}
+
+	preview, err := backup.RunInPlaceRestore(command, config, executor, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("preview returned error: %v", err)
+	}
+	for _, expected := range []string{"create    " + filepath.Join(root, "missing.txt"), "overwrite " + filepath.Join(root, "older.txt"), "skip      " + filepath.Join(root, "newer.txt"), "1 to create, 1 to overwrite, 0 to restore beside the local copy, 1 skipped, 1 unchanged", "rerun with --yes"} {
+		if !strings.Contains(preview, expected) {
+			t.Fatalf("expected %q in preview:\n%s", expected, preview)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "missing.txt")); !os.IsNotExist(err) {
+		t.Fatal("preview must not restore anything")
+	}
+
+	command.Yes = true
+	var progress bytes.Buffer
+	output, err := backup.RunInPlaceRestore(command, config, executor, &progress)
+	if err != nil {
+		t.Fatalf("RunInPlaceRestore returned error: %v", err)
+	}
+	if !strings.Contains(output, "restored 2 file(s)") || !strings.Contains(output, "snapshot ffff6666") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	for name, expected := range map[string]string{"missing.txt": "missing", "older.txt": "restored", "newer.txt": "edited later"} {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(content) != expected {
+			t.Fatalf("unexpected %s: %q, %v", name, content, err)
+		}
+	}
+	backupIndex, restoreIndex := -1, -1
+	for index, call := range executor.calls {
+		if strings.Contains(call, " backup ") {
+			backupIndex = index
+		}
+		if strings.Contains(call, " restore eeee5555") {
+			restoreIndex = index
+		}
+	}
+	if backupIndex < 0 || restoreIndex < backupIndex {
+		t.Fatalf("expected pre-restore backup before restore: %#v", executor.calls)
+	}
+	if strings.Contains(executor.calls[restoreIndex], "--include ") || strings.Join(executor.includes, ",") != root+"/missing.txt,"+root+"/older.txt" {
+		t.Fatalf("expected the restored files in an include file, got %q with %#v", executor.calls[restoreIndex], executor.includes)
+	}
+}
+
+func TestRunInPlaceRestoreTakesTheLockBeforePlanning(t *testing.T) {
+	held, err := backup.AcquireInstanceLock("run daily", false)
+	if err != nil {
+		t.Fatalf("AcquireInstanceLock returned error: %v", err)
+	}
+	defer held.Release()
+
+	executor := &scriptedExecutor{}
+	config := backup.AppConfig{Exists: true, Profiles: map[string]backup.ProfileConfig{"wsl": {RepositoryHint: "/repo"}}}
+	command := backup.Command{Name: "restore", InPlace: true, Yes: true}
+	if _, err := backup.RunInPlaceRestore(command, config, executor, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "run daily") {
+		t.Fatalf("expected the held lock to stop the restore, got %v", err)
+	}
+	if len(executor.calls) != 0 {
+		t.Fatalf("expected no restic calls before the lock, got %#v", executor.calls)
+	}
+}
+
+func TestBuildInPlaceRestorePlanAbortsAndRenames(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	localPath := filepath.Join(root, "doc.txt")
+	if err := os.WriteFile(localPath, []byte("local"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	files := map[string]backup.SnapshotFile{root + "/doc.txt": {Size: 9, ModTime: time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)}}
+	snapshot := backup.Snapshot{ID: "eeee5555", ShortID: "eeee5555"}
+
+	if _, err := backup.BuildInPlaceRestorePlan("wsl", snapshot, files, "", backup.ConflictAbort, ".restored"); err == nil || !strings.Contains(err.Error(), localPath) {
+		t.Fatalf("expected abort naming the conflict, got %v", err)
+	}
+	plan, err := backup.BuildInPlaceRestorePlan("wsl", snapshot, files, root, backup.ConflictRename, ".restored")
+	if err != nil {
+		t.Fatalf("BuildInPlaceRestorePlan returned error: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Destination != localPath+".restored" {
+		t.Fatalf("unexpected rename plan: %#v", plan.Actions)
+	}
+
+	if err := os.WriteFile(localPath+".restored", []byte("earlier restore"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	files[root+"/doc.txt.restored.1"] = backup.SnapshotFile{Size: 3, ModTime: time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)}
+	plan, err = backup.BuildInPlaceRestorePlan("wsl", snapshot, files, root, backup.ConflictRename, ".restored")
+	if err != nil {
+		t.Fatalf("BuildInPlaceRestorePlan returned error: %v", err)
+	}
+	if len(plan.Actions) != 2 || plan.Actions[0].Destination != localPath+".restored.2" {
+		t.Fatalf("expected a rename past existing and restored names, got %#v", plan.Actions)
+	}
+}
+
+func TestParseArgsRestoreInPlace(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"restore", "--in-place", "--profile=windows", "--conflict=rename", "--suffix=.old", "--yes"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if !command.InPlace || command.Profile != "windows" || command.Conflict != "rename" || command.Suffix != ".old" || !command.Yes {
+		t.Fatalf("unexpected restore command: %#v", command)
+	}
+	if _, err := backup.ParseArgs([]string{"restore", "/tmp/out", "--in-place"}); err == nil {
+		t.Fatal("expected target with --in-place to be rejected")
+	}
+	if _, err := backup.ParseArgs([]string{"restore", "/tmp/out", "--yes"}); err == nil {
+		t.Fatal("expected --yes without --in-place to be rejected")
+	}
+	if _, err := backup.ParseArgs([]string{"restore", "--in-place", "--conflict=merge"}); err == nil {
+		t.Fatal("expected invalid conflict policy to be rejected")
+	}
+}
+
+func TestBuildInPlaceRestorePlanTranslatesWindowsPaths(t *testing.T) {
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	files := map[string]backup.SnapshotFile{
+		"/C/Users/me/Documents/a.docx": {Size: 1},
+		"/C/Users/me/Music/b.mp3":      {Size: 1},
+	}
+	plan, err := backup.BuildInPlaceRestorePlan("windows", backup.Snapshot{ShortID: "eeee5555"}, files, `C:\Users\me\Documents`, backup.ConflictSkip, ".restored")
+	if err != nil {
+		t.Fatalf("BuildInPlaceRestorePlan returned error: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].LocalPath != "/mnt/c/Users/me/Documents/a.docx" {
+		t.Fatalf("unexpected windows plan: %#v", plan.Actions)
+	}
+}
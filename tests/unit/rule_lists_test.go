@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+func largeRulePlanConfig(includeCount int) (backup.RunPlan, backup.AppConfig) {
+	includes := make([]string, 0, includeCount)
+	for index := 0; index < includeCount; index++ {
+		includes = append(includes, fmt.Sprintf(`C:\Users\me\Documents\project-%04d`, index))
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{
+		"windows": {
+			IncludeByCadence: backup.CadencePaths{Daily: includes},
+			ExcludeByCadence: backup.CadencePaths{Daily: []string{`C:\Users\me\AppData\Local\Temp`, "/mnt/c/Users/me/Downloads", "**/node_modules"}},
+			RepositoryHint:   `C:\repo`,
+			Host:             "workstation",
+		},
+	}}
+	return backup.RunPlan{Cadence: "daily", Targets: []string{"windows"}}, config
+}
+
+func TestSpillRuleListsKeepsShortCommandLines(t *testing.T) {
+	plan, config := largeRulePlanConfig(3)
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+
+	spilled, cleanup, err := backup.SpillRuleLists(invocations)
+	if err != nil {
+		t.Fatalf("SpillRuleLists returned error: %v", err)
+	}
+	defer cleanup()
+	if strings.Join(spilled[0].Args, " ") != strings.Join(invocations[0].Args, " ") {
+		t.Fatalf("expected args unchanged, got %#v", spilled[0].Args)
+	}
+}
+
+func TestSpillRuleListsWritesTranslatedListFilesForRestic(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator {
+		return backup.NewPathTranslator("", "", "Ubuntu")
+	})
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	plan, config := largeRulePlanConfig(1000)
+	profile := config.Profiles["windows"]
+	profile.IncludeByCadence.Daily[1] = "/mnt/c/Users/me/Pictures"
+	config.Profiles["windows"] = profile
+	invocations, err := backup.BuildResticInvocations(plan, config)
+	if err != nil {
+		t.Fatalf("BuildResticInvocations returned error: %v", err)
+	}
+	spilled, cleanup, err := backup.SpillRuleLists(invocations)
+	if err != nil {
+		t.Fatalf("SpillRuleLists returned error: %v", err)
+	}
+
+	args := spilled[0].Args
+	if len(args) != 16 || args[12] != "--exclude-file" || args[14] != "--files-from-verbatim" {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+	listDirs, _ := filepath.Glob(filepath.Join(stateDir, "tmp", "rule-lists-*"))
+	if len(listDirs) != 1 {
+		t.Fatalf("expected one rule list dir, got %#v", listDirs)
+	}
+	includeList := filepath.Join(listDirs[0], "windows.include.txt")
+	if expected := `\\wsl.localhost\Ubuntu` + strings.ReplaceAll(includeList, "/", `\`); args[15] != expected {
+		t.Fatalf("expected translated include list %q, got %q", expected, args[15])
+	}
+	content, err := os.ReadFile(includeList)
+	if err != nil {
+		t.Fatalf("read include list: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 1000 || lines[0] != `C:\Users\me\Documents\project-0000` || lines[1] != `C:\Users\me\Pictures` {
+		t.Fatalf("unexpected include list: %d lines starting %q", len(lines), lines[:2])
+	}
+	excludeContent, _ := os.ReadFile(filepath.Join(listDirs[0], "windows.exclude.txt"))
+	if string(excludeContent) != "C:\\Users\\me\\AppData\\Local\\Temp\nC:\\Users\\me\\Downloads\n**/node_modules\n" {
+		t.Fatalf("unexpected exclude list: %q", excludeContent)
+	}
+
+	cleanup()
+	if _, err := os.Stat(listDirs[0]); !os.IsNotExist(err) {
+		t.Fatalf("expected rule list dir removed, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package unit
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files under testdata")
+
+func assertGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+	goldenPath := filepath.Join("testdata", "schedule", name)
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, []byte(actual), 0o644); err != nil {
+			t.Fatalf("write golden %s: %v", goldenPath, err)
+		}
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden %s: %v", goldenPath, err)
+	}
+	if string(expected) != actual {
+		t.Fatalf("%s does not match golden output\n--- expected\n%s\n--- actual\n%s", name, expected, actual)
+	}
+}
+
+func TestBuildSystemdUnitsMatchGolden(t *testing.T) {
+	t.Parallel()
+
+	files := backup.BuildSystemdUnits("/usr/local/bin/backup", "/home/me/.config/backup/restic.env")
+	if len(files) != 6 {
+		t.Fatalf("unexpected unit count: %d", len(files))
+	}
+	for _, file := range files {
+		assertGolden(t, file.Name, file.Content)
+	}
+}
+
+func TestBuildWindowsTaskXMLMatchesGolden(t *testing.T) {
+	t.Parallel()
+
+	for _, cadence := range []string{"daily", "weekly", "monthly"} {
+		content, err := backup.BuildWindowsTaskXML(cadence, "Ubuntu Preview", "/usr/local/bin/backup", "/home/me/.config/backup/restic.env")
+		if err != nil {
+			t.Fatalf("BuildWindowsTaskXML(%s) returned error: %v", cadence, err)
+		}
+		assertGolden(t, "backup-"+cadence+".xml", content)
+	}
+}
+
+func TestParseArgsSchedule(t *testing.T) {
+	t.Parallel()
+
+	command, err := backup.ParseArgs([]string{"schedule", "install", "--windows"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if command.Name != "schedule" || command.Action != "install" || !command.Windows {
+		t.Fatalf("unexpected command: %#v", command)
+	}
+	if _, err := backup.ParseArgs([]string{"schedule", "enable"}); err == nil || err.Error() != "unknown schedule action: enable" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func stubScheduleEnvironment(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	t.Setenv("BACKUP_CONFIG", "")
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	t.Cleanup(func() {
+		backup.SetExecutablePathForTests(nil)
+		backup.SetPathTranslatorForTests(nil)
+	})
+	backup.SetExecutablePathForTests(func() (string, error) { return "/usr/local/bin/backup", nil })
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	return tempDir
+}
+
+func TestInstallScheduleWritesUnitsAndEnablesTimers(t *testing.T) {
+	configHome := stubScheduleEnvironment(t)
+
+	executor := &scriptedExecutor{}
+	if _, err := backup.InstallSchedule(false, executor); err != nil {
+		t.Fatalf("InstallSchedule returned error: %v", err)
+	}
+
+	unitDir := filepath.Join(configHome, "systemd", "user")
+	timer, err := os.ReadFile(filepath.Join(unitDir, "backup-weekly.timer"))
+	if err != nil {
+		t.Fatalf("read timer: %v", err)
+	}
+	if !strings.Contains(string(timer), "Persistent=true") {
+		t.Fatalf("timer missing catch-up: %s", timer)
+	}
+	service, err := os.ReadFile(filepath.Join(unitDir, "backup-weekly.service"))
+	if err != nil {
+		t.Fatalf("read service: %v", err)
+	}
+	if !strings.Contains(string(service), "EnvironmentFile=-"+filepath.Join(configHome, "backup", "restic.env")) {
+		t.Fatalf("service missing credentials file: %s", service)
+	}
+	expectedCalls := []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable --now backup-daily.timer backup-weekly.timer backup-monthly.timer",
+	}
+	if strings.Join(executor.calls, "\n") != strings.Join(expectedCalls, "\n") {
+		t.Fatalf("unexpected calls: %#v", executor.calls)
+	}
+}
+
+func TestInstallScheduleWindowsReplacesSystemdTimers(t *testing.T) {
+	configHome := stubScheduleEnvironment(t)
+	if _, err := backup.InstallSchedule(false, &scriptedExecutor{}); err != nil {
+		t.Fatalf("InstallSchedule returned error: %v", err)
+	}
+
+	executor := &scriptedExecutor{}
+	output, err := backup.InstallSchedule(true, executor)
+	if err != nil {
+		t.Fatalf("InstallSchedule returned error: %v", err)
+	}
+
+	taskXML, err := os.ReadFile(filepath.Join(configHome, "backup", "schedule", "backup-daily.xml"))
+	if err != nil {
+		t.Fatalf("read task xml: %v", err)
+	}
+	if len(taskXML) < 2 || taskXML[0] != 0xFF || taskXML[1] != 0xFE {
+		t.Fatalf("task xml should be UTF-16LE with BOM")
+	}
+
+	expectedCalls := []string{
+		`schtasks.exe /Create /TN backup\daily /XML \\wsl.localhost\Ubuntu` + strings.ReplaceAll(filepath.Join(configHome, "backup", "schedule", "backup-daily.xml"), "/", `\`) + " /F",
+		`schtasks.exe /Create /TN backup\weekly /XML \\wsl.localhost\Ubuntu` + strings.ReplaceAll(filepath.Join(configHome, "backup", "schedule", "backup-weekly.xml"), "/", `\`) + " /F",
+		`schtasks.exe /Create /TN backup\monthly /XML \\wsl.localhost\Ubuntu` + strings.ReplaceAll(filepath.Join(configHome, "backup", "schedule", "backup-monthly.xml"), "/", `\`) + " /F",
+		"systemctl --user disable --now backup-daily.timer backup-weekly.timer backup-monthly.timer",
+		"systemctl --user daemon-reload",
+	}
+	if strings.Join(executor.calls, "\n") != strings.Join(expectedCalls, "\n") {
+		t.Fatalf("unexpected calls: %#v", executor.calls)
+	}
+	if !strings.Contains(output, `registered windows task backup\monthly`) || !strings.Contains(output, "removed 6 systemd user unit(s)") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	if _, err := os.Stat(filepath.Join(configHome, "systemd", "user", "backup-daily.timer")); !os.IsNotExist(err) {
+		t.Fatalf("expected timer to be removed, stat err=%v", err)
+	}
+}
+
+func TestScheduleStatusAndRemove(t *testing.T) {
+	configHome := stubScheduleEnvironment(t)
+
+	if _, err := backup.InstallSchedule(false, &scriptedExecutor{}); err != nil {
+		t.Fatalf("InstallSchedule returned error: %v", err)
+	}
+
+	executor := &scriptedExecutor{
+		responses: map[string]string{
+			"systemctl --user is-enabled backup-daily.timer": "enabled",
+			"systemctl --user is-active backup-daily.timer":  "active",
+		},
+		failures: map[string]string{
+			"systemctl --user is-enabled backup-weekly.timer": "disabled",
+		},
+	}
+	output, err := backup.ScheduleStatus(false, executor)
+	if err != nil {
+		t.Fatalf("ScheduleStatus returned error: %v", err)
+	}
+	if !strings.Contains(output, "  daily: enabled, active (*-*-* 02:00:00)") || !strings.Contains(output, "  weekly: disabled, inactive") {
+		t.Fatalf("unexpected status: %q", output)
+	}
+
+	removeExecutor := &scriptedExecutor{}
+	output, err = backup.RemoveSchedule(false, removeExecutor)
+	if err != nil {
+		t.Fatalf("RemoveSchedule returned error: %v", err)
+	}
+	if !strings.Contains(output, "removed 6 systemd user unit(s)") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	if removeExecutor.calls[0] != "systemctl --user disable --now backup-daily.timer backup-weekly.timer backup-monthly.timer" {
+		t.Fatalf("unexpected calls: %#v", removeExecutor.calls)
+	}
+	if _, err := os.Stat(filepath.Join(configHome, "systemd", "user", "backup-daily.service")); !os.IsNotExist(err) {
+		t.Fatalf("expected unit to be removed, stat err=%v", err)
+	}
+}
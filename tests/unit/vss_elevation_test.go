@@ -0,0 +1,277 @@
+package unit
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	backup "wsl-backup-cli/src"
+)
+
+const elevationCheckCall = "powershell.exe -NoProfile -NonInteractive -Command ([Security.Principal.WindowsPrincipal][Security.Principal.WindowsIdentity]::GetCurrent()).IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)"
+
+func vssInvocations() []backup.ResticInvocation {
+	return []backup.ResticInvocation{{Target: "windows", Executable: "restic.exe", Args: []string{"-r", `C:\repo`, "backup", "--json", "--use-fs-snapshot", `C:\Users\me`}}}
+}
+
+func TestExecuteWithHooksFallsBackWithoutVSSWhenNotElevated(t *testing.T) {
+	executor := &scriptedExecutor{responses: map[string]string{elevationCheckCall: "False"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {RepositoryHint: `C:\repo`}}}
+
+	results := backup.ExecuteResticInvocationsWithHooks(vssInvocations(), "daily", config, executor)
+
+	if results[0].Err != nil {
+		t.Fatalf("unexpected error: %v", results[0].Err)
+	}
+	if got := executor.calls[len(executor.calls)-1]; got != `restic.exe -r C:\repo backup --json C:\Users\me` {
+		t.Fatalf("expected restic without --use-fs-snapshot, got %q", got)
+	}
+	if len(results[0].Warnings) != 1 || !strings.Contains(results[0].Warnings[0], "warning: windows: not elevated; backed up without --use-fs-snapshot (VSS)") {
+		t.Fatalf("unexpected warnings: %#v", results[0].Warnings)
+	}
+}
+
+func TestExecuteWithHooksKeepsVSSWhenElevated(t *testing.T) {
+	executor := &scriptedExecutor{responses: map[string]string{elevationCheckCall: "True"}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {RepositoryHint: `C:\repo`}}}
+
+	results := backup.ExecuteResticInvocationsWithHooks(vssInvocations(), "daily", config, executor)
+
+	if results[0].Err != nil || len(results[0].Warnings) != 0 {
+		t.Fatalf("unexpected result: %#v", results[0])
+	}
+	if got := executor.calls[len(executor.calls)-1]; !strings.Contains(got, "--use-fs-snapshot") {
+		t.Fatalf("expected --use-fs-snapshot kept, got %q", got)
+	}
+}
+
+type elevatedTaskExecutor struct {
+	scriptedExecutor
+	taskDir  string
+	exitCode string
+}
+
+func (executor *elevatedTaskExecutor) Run(name string, args ...string) (string, error) {
+	output, err := executor.scriptedExecutor.Run(name, args...)
+	if name == "schtasks.exe" && len(args) > 0 && args[0] == "/Run" {
+		request, _ := os.ReadFile(filepath.Join(executor.taskDir, "request.json"))
+		executor.calls = append(executor.calls, "request "+string(request))
+		_ = os.WriteFile(filepath.Join(executor.taskDir, "output.txt"), []byte("\ufeffsnapshot 1a2b3c saved\r\n"), 0o644)
+		_ = os.WriteFile(filepath.Join(executor.taskDir, "exit.txt"), []byte(executor.exitCode+"\r\n"), 0o644)
+	}
+	return output, err
+}
+
+func TestExecuteWithHooksRunsThroughElevatedTask(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	t.Setenv("RESTIC_PASSWORD", "secret")
+	taskDir := filepath.Join(stateDir, "elevated")
+	if err := os.MkdirAll(taskDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {RepositoryHint: `C:\repo`, VSSElevation: backup.VSSElevationTask}}}
+
+	executor := &elevatedTaskExecutor{scriptedExecutor: scriptedExecutor{responses: map[string]string{elevationCheckCall: "False"}}, taskDir: taskDir, exitCode: "0"}
+	results := backup.ExecuteResticInvocationsWithHooks(vssInvocations(), "daily", config, executor)
+	if results[0].Err != nil || len(results[0].Warnings) != 0 || results[0].Output != "snapshot 1a2b3c saved" {
+		t.Fatalf("unexpected result: %#v", results[0])
+	}
+	request := executor.calls[len(executor.calls)-1]
+	if !strings.Contains(request, `"executable":"restic.exe"`) || !strings.Contains(request, `"--use-fs-snapshot"`) || !strings.Contains(request, `"RESTIC_PASSWORD":"secret"`) {
+		t.Fatalf("unexpected request: %s", request)
+	}
+	if _, err := os.Stat(filepath.Join(taskDir, "request.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected request removed after the run, got %v", err)
+	}
+
+	executor = &elevatedTaskExecutor{scriptedExecutor: scriptedExecutor{responses: map[string]string{elevationCheckCall: "False"}}, taskDir: taskDir, exitCode: "3"}
+	results = backup.ExecuteResticInvocationsWithHooks(vssInvocations(), "daily", config, executor)
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), `restic.exe exited with code 3 in elevated task backup\restic-elevated`) {
+		t.Fatalf("expected elevated exit code, got %v", results[0].Err)
+	}
+}
+
+func TestExecuteWithHooksTranslatesLinuxPathsForElevatedTask(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+	taskDir := filepath.Join(stateDir, "elevated")
+	if err := os.MkdirAll(taskDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {RepositoryHint: "/mnt/d/restic", ResticPath: "/mnt/c/Tools/restic.exe", VSSElevation: backup.VSSElevationTask}}}
+	invocations := []backup.ResticInvocation{{Target: "windows", Executable: "/mnt/c/Tools/restic.exe", Args: []string{"-r", "/mnt/d/restic", "backup", "--json", "--use-fs-snapshot", "--exclude-file", "/mnt/c/Users/me/excludes.txt", "/mnt/c/Users/me"}}}
+
+	executor := &elevatedTaskExecutor{scriptedExecutor: scriptedExecutor{responses: map[string]string{elevationCheckCall: "False"}}, taskDir: taskDir, exitCode: "0"}
+	results := backup.ExecuteResticInvocationsWithHooks(invocations, "daily", config, executor)
+	if results[0].Err != nil {
+		t.Fatalf("unexpected error: %v", results[0].Err)
+	}
+	request := executor.calls[len(executor.calls)-1]
+	expected := `"executable":"C:\\Tools\\restic.exe","args":["-r","D:\\restic","backup","--json","--use-fs-snapshot","--exclude-file","C:\\Users\\me\\excludes.txt","C:\\Users\\me"]`
+	if !strings.Contains(request, expected) {
+		t.Fatalf("expected %s in request: %s", expected, request)
+	}
+}
+
+func TestElevatedTaskRefusesRequestsOutsideTheAllowlist(t *testing.T) {
+	taskDir := t.TempDir()
+	t.Setenv("RESTIC_REPOSITORY", "sftp:attacker@example.test:/repo")
+	t.Setenv("RESTIC_PASSWORD", "secret")
+
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"-r", `C:\repo`, "backup", "-o", `rclone.program=C:\evil.exe`, `C:\Users\me`}, expected: "-o is not accepted elevated"},
+		{args: []string{"-r", `C:\repo`, "backup", "--option=sftp.command=evil.exe", `C:\Users\me`}, expected: "--option=sftp.command=evil.exe is not accepted elevated"},
+		{args: []string{"-r", "sftp:user@host:/repo", "backup", `C:\Users\me`}, expected: "repository sftp:user@host:/repo is not a local drive or UNC path"},
+		{args: []string{"-r", "rclone:remote:repo", "backup", `C:\Users\me`}, expected: "is not a local drive or UNC path"},
+		{args: []string{"-r", `C:\repo`, "backup", "--password-command", "evil.exe", `C:\Users\me`}, expected: "--password-command is not accepted elevated"},
+		{args: []string{"-r", `C:\repo`, "backup", "--no-scan", `C:\Users\me`}, expected: "--no-scan is not accepted elevated"},
+		{args: []string{"-r", `C:\repo`, "backup", "--tag"}, expected: "--tag needs a value"},
+		{args: []string{"-r", `C:\repo`, "forget", "--prune"}, expected: "only restic -r <repository> backup runs elevated"},
+	}
+	for _, testCase := range cases {
+		fake := &elevatedTaskExecutor{taskDir: taskDir, exitCode: "0"}
+		executor := backup.ElevatedTaskExecutor{Executor: fake, TaskName: backup.ElevatedTaskName, Dir: taskDir}
+		_, err := executor.Run(`C:\Tools\restic.exe`, testCase.args...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Fatalf("%v: expected %q, got %v", testCase.args, testCase.expected, err)
+		}
+		if len(fake.calls) != 0 {
+			t.Fatalf("%v: a refused request must not start the task, got %#v", testCase.args, fake.calls)
+		}
+	}
+
+	fake := &elevatedTaskExecutor{taskDir: taskDir, exitCode: "0"}
+	executor := backup.ElevatedTaskExecutor{Executor: fake, TaskName: backup.ElevatedTaskName, Dir: taskDir}
+	args := []string{"-r", `\\nas\backups\repo`, "backup", "--json", "--host", "pc", "--tag", "cadence:daily", "--use-fs-snapshot", "--exclude-caches", "--compression", "max", "--exclude", `C:\Users\me\tmp`, "--files-from-verbatim", `C:\lists\include.txt`}
+	if _, err := executor.Run(`C:\Tools\restic.exe`, args...); err != nil {
+		t.Fatalf("unexpected error for an allowlisted request: %v", err)
+	}
+	request := fake.calls[len(fake.calls)-1]
+	if strings.Contains(request, "RESTIC_REPOSITORY") || !strings.Contains(request, `"RESTIC_PASSWORD":"secret"`) {
+		t.Fatalf("expected RESTIC_REPOSITORY to stay out of the request: %s", request)
+	}
+}
+
+func TestExecuteWithHooksFallsBackWhenElevatedTaskMissing(t *testing.T) {
+	t.Setenv("BACKUP_STATE_DIR", t.TempDir())
+	executor := &scriptedExecutor{
+		responses: map[string]string{elevationCheckCall: "False"},
+		failures:  map[string]string{`schtasks.exe /Query /TN backup\restic-elevated`: "ERROR: The system cannot find the file specified."},
+	}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {RepositoryHint: `C:\repo`, VSSElevation: backup.VSSElevationTask}}}
+
+	results := backup.ExecuteResticInvocationsWithHooks(vssInvocations(), "daily", config, executor)
+
+	if len(results[0].Warnings) != 2 || !strings.Contains(results[0].Warnings[0], "elevated task unavailable") {
+		t.Fatalf("unexpected warnings: %#v", results[0].Warnings)
+	}
+}
+
+func TestBuildElevatedTaskXMLRunsRunnerWithHighestPrivileges(t *testing.T) {
+	t.Parallel()
+
+	content := backup.BuildElevatedTaskXML(`%ProgramData%\backup\restic-elevated.ps1`)
+	for _, expected := range []string{
+		"<RunLevel>HighestAvailable</RunLevel>",
+		"<AllowStartOnDemand>true</AllowStartOnDemand>",
+		`-File &quot;%ProgramData%\backup\restic-elevated.ps1&quot;`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("expected %q in task xml:\n%s", expected, content)
+		}
+	}
+}
+
+func TestBuildElevatedRunnerScriptPinsResticBackup(t *testing.T) {
+	t.Parallel()
+
+	script := backup.BuildElevatedRunnerScript(`C:\Program Files\restic\restic.exe`, `\\wsl.localhost\Ubuntu\home\me\.local\state\backup\elevated`)
+	for _, expected := range []string{
+		`$restic = 'C:\Program Files\restic\restic.exe'`,
+		`$dir = '\\wsl.localhost\Ubuntu\home\me\.local\state\backup\elevated'`,
+		"if ($requested -ne $restic)",
+		"$resticArgs[0] -cne '-r' -or $resticArgs[2] -cne 'backup'",
+		`if ($resticArgs[1] -notmatch '^([A-Za-z]:\\|\\\\[^\\])')`,
+		"$switchFlags = @('--json', '--use-fs-snapshot', '--exclude-caches', '--one-file-system')",
+		"'--host', '--tag', '--exclude'",
+		"'--files-from-verbatim'",
+		`if ($arg.StartsWith('-')) { Complete "rejected: $arg is not accepted elevated" 1 }`,
+		"'RESTIC_PASSWORD_FILE'",
+		"& $restic @resticArgs",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("expected %q in runner:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(script, "'RESTIC_PASSWORD_COMMAND'") || strings.Contains(script, "'RESTIC_REPOSITORY") || strings.Contains(script, "'-o'") || strings.Contains(script, "$request.executable @") {
+		t.Fatalf("runner must not run requested commands or password commands:\n%s", script)
+	}
+}
+
+func TestInstallElevatedTaskWritesRunnerUnderProgramData(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BACKUP_STATE_DIR", stateDir)
+	t.Setenv("BACKUP_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	backup.SetPathTranslatorForTests(func() backup.PathTranslator { return backup.NewPathTranslator("", "", "Ubuntu") })
+	t.Cleanup(func() { backup.SetPathTranslatorForTests(nil) })
+
+	resolveCall := "powershell.exe -NoProfile -NonInteractive -Command (Get-Command -CommandType Application -Name 'C:\\Tools\\restic.exe' -ErrorAction Stop | Select-Object -First 1).Source"
+	executor := &scriptedExecutor{responses: map[string]string{
+		elevationCheckCall: "True",
+		resolveCall:        "C:\\Tools\\restic.exe\r\n",
+	}}
+	config := backup.AppConfig{Profiles: map[string]backup.ProfileConfig{"windows": {ResticPath: "/mnt/c/Tools/restic.exe"}}}
+
+	line, err := backup.InstallElevatedTask(config, executor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(line, `for C:\Tools\restic.exe`) {
+		t.Fatalf("unexpected line: %q", line)
+	}
+
+	var installScript string
+	for _, call := range executor.calls {
+		if encoded, ok := strings.CutPrefix(call, "powershell.exe -NoProfile -NonInteractive -EncodedCommand "); ok {
+			raw, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatalf("decode install script: %v", err)
+			}
+			installScript = backup.DecodeConsoleOutput(raw)
+		}
+	}
+	for _, expected := range []string{
+		"$dir = Join-Path $env:ProgramData 'backup'",
+		"Remove-Item -LiteralPath $dir -Recurse -Force",
+		"/inheritance:r /grant:r '*S-1-5-32-544:(OI)(CI)F' '*S-1-5-18:(OI)(CI)F' '*S-1-5-32-545:(OI)(CI)RX'",
+		"$restic = ''C:\\Tools\\restic.exe''",
+		"$dir = ''\\\\wsl.localhost\\Ubuntu" + strings.ReplaceAll(filepath.Join(stateDir, "elevated"), "/", `\`) + "''",
+	} {
+		if !strings.Contains(installScript, expected) {
+			t.Fatalf("expected %q in install script:\n%s", expected, installScript)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "elevated", "runner.ps1")); !os.IsNotExist(err) {
+		t.Fatalf("expected no runner in the state dir, got %v", err)
+	}
+}
+
+func TestParseScheduleElevatedRequiresWindows(t *testing.T) {
+	t.Parallel()
+
+	if _, err := backup.ParseArgs([]string{"schedule", "install", "--elevated"}); err == nil || err.Error() != "--elevated requires --windows" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	command, err := backup.ParseArgs([]string{"schedule", "install", "--windows", "--elevated"})
+	if err != nil || !command.Windows || !command.Elevated {
+		t.Fatalf("unexpected command: %#v, %v", command, err)
+	}
+}